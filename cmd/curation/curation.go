@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/aattwwss/ssd-bot-go/internal/curation"
+	"github.com/rs/zerolog/log"
+)
+
+// curation lists the submissions the bot found no drive for, grouped by
+// likely manufacturer, e.g.
+//
+//	go run ./cmd/curation -manufacturer samsung
+func main() {
+	in := flag.String("in", "data/misses.jsonl", "Misses file written by the bot, see MISSES_FILE")
+	manufacturer := flag.String("manufacturer", "", "Only list the misses of this manufacturer")
+	candidates := flag.Int("candidates", 3, "Search candidates to list per miss")
+	flag.Parse()
+
+	misses, err := curation.NewFileStore(*in).All()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read misses error")
+	}
+
+	groups := curation.GroupByManufacturer(misses)
+	if *manufacturer != "" {
+		var filtered []curation.Group
+		for _, g := range groups {
+			if strings.EqualFold(g.Manufacturer, *manufacturer) {
+				filtered = append(filtered, g)
+			}
+		}
+		groups = filtered
+	}
+	if err := curation.WriteReport(os.Stdout, groups, *candidates); err != nil {
+		log.Fatal().Err(err).Msg("Report error")
+	}
+	log.Info().Msgf("Listed %d groups of %d misses", len(groups), len(misses))
+}
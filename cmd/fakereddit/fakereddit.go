@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/rs/zerolog/log"
+)
+
+// fakereddit serves an in-memory Reddit API so the bot can be run offline.
+// Point the bot at it with REDDIT_BASE_URL and REDDIT_AUTH_URL.
+func main() {
+	addr := flag.String("addr", "localhost:8081", "Address to listen on")
+	subreddit := flag.String("subreddit", "buildapcsales", "Subreddit to seed submissions into")
+	seed := flag.String("seed", "test/input.csv", "Tab separated file of submission id and title to seed, empty to skip")
+	flag.Parse()
+
+	fake := reddittest.New()
+	if *seed != "" {
+		n, err := seedSubmissions(fake, *subreddit, *seed)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Seed error")
+		}
+		log.Info().Msgf("Seeded %d submissions into r/%s", n, *subreddit)
+	}
+
+	log.Info().Msgf("Fake reddit listening on http://%s, auth url http://%s/api/v1/access_token", *addr, *addr)
+	if err := http.ListenAndServe(*addr, fake); err != nil {
+		log.Fatal().Err(err).Msg("Listen error")
+	}
+}
+
+func seedSubmissions(fake *reddittest.Server, subreddit, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("opening seed file: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comma = '\t'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("reading seed records: %w", err)
+	}
+	// seed oldest first so the first line ends up as the newest submission
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if len(record) < 2 {
+			continue
+		}
+		fake.AddSubmission(reddit.Submission{
+			ID:            record[0],
+			Subreddit:     subreddit,
+			Title:         record[1],
+			LinkFlairText: "SSD",
+		})
+	}
+	return len(records), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/aattwwss/ssd-bot-go/internal/feedback"
+	"github.com/rs/zerolog/log"
+)
+
+// feedback exports the labelled corrections collected by the bot in the
+// layout of test/test_data.csv, e.g.
+//
+//	go run ./cmd/feedback -out test/test_data.csv -append
+func main() {
+	in := flag.String("in", "data/feedback.jsonl", "Feedback file written by the bot, see FEEDBACK_FILE")
+	out := flag.String("out", "", "Tab separated file to write, empty for stdout")
+	appendOut := flag.Bool("append", false, "Append to the output file instead of replacing it")
+	flag.Parse()
+
+	records, err := feedback.NewFileStore(*in).All()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Read feedback error")
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if *appendOut {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		f, err := os.OpenFile(*out, flags, 0644)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Open output error")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := feedback.WriteTSV(w, records)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Export error")
+	}
+	log.Info().Msgf("Exported %d of %d feedback records", n, len(records))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/elasticutil"
+	"github.com/aattwwss/ssd-bot-go/internal/bot"
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/redditclient"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/caarlos0/env/v8"
+	"github.com/joho/godotenv"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ES_INDEX           = "ssd-index"
+	PAGE_SIZE          = 100
+	DEFAULT_MAX_PAGES  = 10
+	COMMENT_RATE_LIMIT = 1 * time.Second
+)
+
+type rerenderParam struct {
+	DriveID  string
+	MaxPages int
+	DryRun   bool
+	// Regions maps a lowercase subreddit name to its price history region
+	Regions map[string]string
+}
+
+// rerender updates the bot's past comments for a drive with freshly rendered
+// specs, e.g. after TechPowerUp corrected the drive's data.
+func main() {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal().Msg("Error loading .env file")
+	}
+
+	cfg := config.Config{}
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatal().Msgf("Parse env error: %v", err)
+	}
+	driveId := flag.String("driveId", "", "Drive ID whose comments should be updated")
+	maxPages := flag.Int("maxPages", DEFAULT_MAX_PAGES, "Number of pages of the bot's comments to search")
+	dryRun := flag.Bool("dryRun", false, "Only log the comments that would be updated")
+	flag.Parse()
+	if *driveId == "" {
+		log.Fatal().Msg("driveId is required")
+	}
+
+	redditOpts, err := redditclient.Options(cfg)
+	if err != nil {
+		log.Fatal().Msgf("Reddit config error: %v", err)
+	}
+	rc, err := reddit.NewRedditClient(cfg.ClientId, cfg.ClientSecret, cfg.Username, cfg.Password, cfg.Token, cfg.ExpireTimeMilli, cfg.OverrideOldBot, redditOpts...)
+	if err != nil {
+		log.Fatal().Msgf("Init reddit client error: %v", err)
+	}
+	es, err := elasticutil.NewElasticsearchClient(cfg.EsAddress)
+	if err != nil {
+		log.Fatal().Msgf("Init elasticsearch client error: %v", err)
+	}
+	esRepo := ssd.NewEsRepository(es, ES_INDEX)
+
+	rules, err := cfg.SubredditRules()
+	if err != nil {
+		log.Fatal().Msgf("Subreddit rules error: %v", err)
+	}
+	param := rerenderParam{
+		DriveID:  *driveId,
+		MaxPages: *maxPages,
+		DryRun:   *dryRun,
+		Regions:  map[string]string{},
+	}
+	for _, rule := range rules {
+		param.Regions[strings.ToLower(rule.Name)] = rule.Region
+	}
+	updated, err := rerender(context.Background(), rc, esRepo, param)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Rerender error")
+	}
+	log.Info().Msgf("Updated %d comments for drive %s", updated, param.DriveID)
+}
+
+// rerender edits every comment of the bot showing the drive whose text
+// differs from the current rendering, and returns how many it updated.
+// Comments showing several drives, e.g. near ties or bundle posts, are
+// rendered again with all of them.
+func rerender(ctx context.Context, rc *reddit.Client, repo ssd.Repository, p rerenderParam) (int, error) {
+	found, err := repo.FindById(ctx, p.DriveID)
+	if err != nil {
+		return 0, err
+	}
+	if found == nil {
+		log.Info().Msgf("Drive not found in database: %s", p.DriveID)
+		return 0, nil
+	}
+	// drives by ID, nil for those missing from the database
+	drives := map[string]*ssd.SSD{p.DriveID: found}
+	renderer := bot.MarkdownRenderer{}
+	updated := 0
+	comments := rc.UserComments(reddit.ListingOptions{Limit: PAGE_SIZE}, p.MaxPages)
+	for comments.HasNext() {
+		page, err := comments.Next(ctx)
+		if err != nil {
+			return updated, err
+		}
+		for _, comment := range page {
+			ids := ssd.ParseDriveIDs(comment.Body)
+			if !slices.Contains(ids, p.DriveID) {
+				continue
+			}
+			shown := make([]ssd.SSD, 0, len(ids))
+			for _, id := range ids {
+				drive, ok := drives[id]
+				if !ok {
+					if drive, err = repo.FindById(ctx, id); err != nil {
+						return updated, err
+					}
+					drives[id] = drive
+				}
+				if drive != nil {
+					shown = append(shown, *drive)
+				}
+			}
+			if len(shown) != len(ids) {
+				log.Info().Msgf("Not updating comment %s, some of its drives %v are not in the database", comment.Name, ids)
+				continue
+			}
+			region := p.Regions[strings.ToLower(comment.Subreddit)]
+			var markdown string
+			switch {
+			case bot.IsNearTie(comment.Body):
+				markdown = renderer.RenderNearTie(shown, region)
+			case len(shown) > 1:
+				// a comment on another capacity keeps its note
+				markdown = ssd.CapacityNote(comment.Body) + renderer.RenderMany(shown, region)
+			default:
+				markdown = ssd.CapacityNote(comment.Body) + renderer.Render(shown[0], region)
+			}
+			if comment.Body == markdown {
+				continue
+			}
+			if p.DryRun {
+				log.Info().Msgf("Would update comment %s on %s", comment.Name, comment.LinkID)
+				updated++
+				continue
+			}
+			_, err := rc.EditComment(ctx, comment.Name, markdown)
+			if err != nil {
+				log.Error().Msgf("Error updating comment %s: %v", comment.Name, err)
+				continue
+			}
+			log.Info().Msgf("Updated comment %s on %s", comment.Name, comment.LinkID)
+			updated++
+			time.Sleep(COMMENT_RATE_LIMIT)
+		}
+	}
+	return updated, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/internal/bot"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+func TestRerender(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	drive := ssd.SSD{DriveID: "1100", Manufacturer: "Solidigm", Name: "P44 Pro", Capacity: "2 TB", URL: "https://www.techpowerup.com/ssd-specs/solidigm-p44-pro-2-tb.d1100", Endurance: "1200 TBW"}
+	other := ssd.SSD{DriveID: "1461", Manufacturer: "Corsair", Name: "MP600 Mini", Capacity: "1 TB", URL: "https://www.techpowerup.com/ssd-specs/corsair-mp600-mini-1-tb.d1461"}
+	repo := ssdtest.NewRepository(drive, other)
+	renderer := bot.MarkdownRenderer{}
+
+	stale := drive
+	stale.Endurance = "1000 TBW"
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "bapcsalescanada", Title: "[SSD] Solidigm P44 Pro 2TB"})
+	matching := fake.AddComment(submission.ID, "_SSD_BOT_", stale.ToMarkdownRegion("ca"))
+	multi := fake.AddComment(submission.ID, "_SSD_BOT_", ssd.MultiMarkdownRegion([]ssd.SSD{stale, other}, "ca"))
+	nearTie := fake.AddComment(submission.ID, "_SSD_BOT_", renderer.RenderNearTie([]ssd.SSD{stale, other}, "ca"))
+	unknown := ssd.SSD{DriveID: "1", Manufacturer: "Crucial", Name: "P3", URL: "https://www.techpowerup.com/ssd-specs/crucial-p3-1-tb.d1"}
+	withUnknown := fake.AddComment(submission.ID, "_SSD_BOT_", ssd.MultiMarkdownRegion([]ssd.SSD{stale, unknown}, "ca"))
+	unchanged := fake.AddComment(submission.ID, "_SSD_BOT_", drive.ToMarkdownRegion("ca"))
+
+	param := rerenderParam{DriveID: "1100", MaxPages: 1, Regions: map[string]string{"bapcsalescanada": "ca"}}
+	updated, err := rerender(context.Background(), rc, repo, param)
+	if err != nil {
+		t.Fatalf("rerender() unexpected error = %v", err)
+	}
+	if updated != 3 {
+		t.Errorf("rerender() = %d, want 3", updated)
+	}
+	bodies := map[string]string{}
+	for _, c := range fake.CommentsBy("_SSD_BOT_") {
+		bodies[c.Name] = c.Body
+	}
+	if got, want := bodies[matching.Name], drive.ToMarkdownRegion("ca"); got != want {
+		t.Errorf("matching comment = %q, want %q", got, want)
+	}
+	if got, want := bodies[multi.Name], ssd.MultiMarkdownRegion([]ssd.SSD{drive, other}, "ca"); got != want {
+		t.Errorf("multi-drive comment = %q, want %q", got, want)
+	}
+	if got, want := bodies[nearTie.Name], renderer.RenderNearTie([]ssd.SSD{drive, other}, "ca"); got != want {
+		t.Errorf("near tie comment = %q, want %q", got, want)
+	}
+	if got := bodies[withUnknown.Name]; got != withUnknown.Body {
+		t.Errorf("comment with an unknown drive = %q, want it untouched", got)
+	}
+	if got := bodies[unchanged.Name]; got != unchanged.Body {
+		t.Errorf("unchanged comment = %q, want it untouched", got)
+	}
+}
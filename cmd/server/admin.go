@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aattwwss/ssd-bot-go/internal/bot"
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/store"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ADMIN_LIST_LIMIT is how many decisions GET /admin/submissions returns
+	// without a limit parameter.
+	ADMIN_LIST_LIMIT = 50
+)
+
+// adminAPI lets the operator inspect and steer the running bot. Every route
+// needs the admin token as a bearer token.
+type adminAPI struct {
+	token string
+	// nearTie is the MATCH_NEAR_TIE margin of the previews
+	nearTie   float64
+	processed store.Store
+	matcher   bot.Matcher
+	renderer  bot.Renderer
+	reddit    bot.SubmissionLookup
+	// pipelines by lower case subreddit name
+	pipelines map[string]*bot.Pipeline
+	// paused holds back the comments of the pipelines and the inbox replies
+	paused *atomic.Bool
+}
+
+func newAdminAPI(cfg config.Config, processed store.Store, matcher bot.Matcher, rc bot.SubmissionLookup, pipelines []*bot.Pipeline, paused *atomic.Bool) *adminAPI {
+	a := &adminAPI{
+		token:     cfg.AdminToken,
+		nearTie:   cfg.MatchNearTie,
+		processed: processed,
+		matcher:   matcher,
+		renderer:  bot.MarkdownRenderer{},
+		reddit:    rc,
+		pipelines: map[string]*bot.Pipeline{},
+		paused:    paused,
+	}
+	for _, p := range pipelines {
+		a.pipelines[strings.ToLower(p.Rule.Name)] = p
+	}
+	return a
+}
+
+func (a *adminAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/submissions", a.authorized(a.handleList))
+	mux.HandleFunc("GET /admin/preview", a.authorized(a.handlePreview))
+	mux.HandleFunc("POST /admin/submissions/{id}/reprocess", a.authorized(a.handleReprocess))
+	mux.HandleFunc("POST /admin/submissions/{id}/ignore", a.authorized(a.handleIgnore))
+	mux.HandleFunc("GET /admin/posting", a.authorized(a.handlePosting))
+	mux.HandleFunc("POST /admin/posting/pause", a.authorized(a.handlePause))
+	mux.HandleFunc("POST /admin/posting/resume", a.authorized(a.handleResume))
+}
+
+func (a *adminAPI) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleList returns the latest decisions, newest first, optionally only
+// those with the given outcome, e.g. ?outcome=noMatch&limit=20.
+func (a *adminAPI) handleList(w http.ResponseWriter, r *http.Request) {
+	limit := ADMIN_LIST_LIMIT
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	outcome := store.Outcome(r.URL.Query().Get("outcome"))
+
+	records, err := a.processed.Recent(-1)
+	if err != nil {
+		log.Error().Msgf("Admin list error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res := []store.Record{}
+	for _, record := range records {
+		if len(res) == limit {
+			break
+		}
+		if outcome == "" || record.Outcome == outcome {
+			res = append(res, record)
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// preview is the match and comment the bot would post for a title.
+type preview struct {
+	Title    string         `json:"title"`
+	Match    bot.TitleMatch `json:"match"`
+	Markdown string         `json:"markdown"`
+}
+
+// handlePreview matches ?title= and renders the comment for the price
+// history ?region=, without posting anything.
+func (a *adminAPI) handlePreview(w http.ResponseWriter, r *http.Request) {
+	title := r.URL.Query().Get("title")
+	if strings.TrimSpace(title) == "" {
+		http.Error(w, "title is required", http.StatusBadRequest)
+		return
+	}
+	match, err := bot.MatchTitle(r.Context(), a.matcher, title)
+	if err != nil {
+		log.Error().Msgf("Admin preview error: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	res := preview{
+		Title:    title,
+		Match:    match,
+		Markdown: match.Render(a.renderer, r.URL.Query().Get("region"), a.nearTie),
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleReprocess queues a submission, so the pipeline of its subreddit looks
+// at it again on its next run, even after a restart. A commented submission
+// is refused, its record keeps the comment to rerender and tie feedback to.
+func (a *adminAPI) handleReprocess(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if record, ok, err := a.processed.Get(id); err != nil {
+		log.Error().Msgf("Admin reprocess error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	} else if ok && record.Outcome == store.OutcomeCommented {
+		http.Error(w, "submission is already commented on", http.StatusConflict)
+		return
+	}
+	submissions, err := a.reddit.GetSubmissionsByName(r.Context(), "t3_"+id)
+	if err != nil {
+		log.Error().Msgf("Admin reprocess error: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if len(submissions) == 0 {
+		http.Error(w, "submission not found", http.StatusNotFound)
+		return
+	}
+	submission := submissions[0]
+	if _, ok := a.pipelines[strings.ToLower(submission.Subreddit)]; !ok {
+		http.Error(w, "r/"+submission.Subreddit+" is not watched", http.StatusUnprocessableEntity)
+		return
+	}
+	record := store.Record{
+		SubmissionID: submission.ID,
+		Subreddit:    submission.Subreddit,
+		Title:        submission.Title,
+		Outcome:      store.OutcomeQueued,
+		Reason:       "reprocessed by an admin",
+	}
+	if err := a.processed.Put(record); err != nil {
+		log.Error().Msgf("Admin reprocess error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Info().Msgf("Admin requeued submission %s: %s", id, submission.Title)
+	writeJSON(w, http.StatusAccepted, submission)
+}
+
+// handleIgnore has the bot leave a submission alone, with an optional
+// ?reason=. A commented submission is refused like in handleReprocess.
+func (a *adminAPI) handleIgnore(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	record, _, err := a.processed.Get(id)
+	if err != nil {
+		log.Error().Msgf("Admin ignore error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if record.Outcome == store.OutcomeCommented {
+		http.Error(w, "submission is already commented on", http.StatusConflict)
+		return
+	}
+	record.SubmissionID = id
+	record.Outcome = store.OutcomeIgnored
+	record.Reason = r.URL.Query().Get("reason")
+	if record.Reason == "" {
+		record.Reason = "ignored by an admin"
+	}
+	if err := a.processed.Put(record); err != nil {
+		log.Error().Msgf("Admin ignore error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Info().Msgf("Admin ignored submission %s: %s", id, record.Reason)
+	record, _, _ = a.processed.Get(id)
+	writeJSON(w, http.StatusOK, record)
+}
+
+type postingStatus struct {
+	Paused bool `json:"paused"`
+}
+
+func (a *adminAPI) handlePosting(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, postingStatus{Paused: a.paused.Load()})
+}
+
+func (a *adminAPI) handlePause(w http.ResponseWriter, r *http.Request) {
+	if !a.paused.Swap(true) {
+		log.Info().Msg("Admin paused posting")
+	}
+	writeJSON(w, http.StatusOK, postingStatus{Paused: true})
+}
+
+func (a *adminAPI) handleResume(w http.ResponseWriter, r *http.Request) {
+	if a.paused.Swap(false) {
+		log.Info().Msg("Admin resumed posting")
+	}
+	writeJSON(w, http.StatusOK, postingStatus{Paused: false})
+}
+
+// pausable skips run while posting is paused, leaving the unread messages for
+// the first run after resuming.
+func pausable(paused *atomic.Bool, name string, run func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if paused.Load() {
+			log.Info().Msgf("Posting is paused, not running %s", name)
+			return nil
+		}
+		return run(ctx)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Msgf("Error writing response: %v", err)
+	}
+}
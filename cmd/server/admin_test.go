@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/internal/bot"
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/config/configtest"
+	"github.com/aattwwss/ssd-bot-go/internal/store"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+const testAdminToken = "s3cret"
+
+func adminRequest(t *testing.T, server *httptest.Server, method, path string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, nil)
+	if err != nil {
+		t.Fatalf("NewRequest() unexpected error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s unexpected error = %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestAdminAPI(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	processed := store.NewMemoryStore()
+	cfg := config.Config{Username: "_SSD_BOT_"}
+	pipeline := newPipeline(cfg, configtest.Rule, rc, repo, processed, nil)
+	var paused atomic.Bool
+	pipeline.Paused = &paused
+	admin := newAdminAPI(config.Config{AdminToken: testAdminToken}, processed, bot.NewESMatcher(repo, 0), rc, []*bot.Pipeline{pipeline}, &paused)
+	server := httptest.NewServer(newHTTPHandler(nil, admin))
+	defer server.Close()
+
+	matched := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+	unflaired := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Corsair MP600 Mini 1TB - $70", LinkFlairText: "Other"})
+	ignored := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Corsair MP600 Mini 1TB - $65", LinkFlairText: "SSD"})
+	other := fake.AddSubmission(reddit.Submission{Subreddit: "hardwareswap", Title: "[USA-CA] [H] Solidigm P44 Pro [W] PayPal"})
+
+	if code, _ := get(t, server, "/admin/submissions"); code != http.StatusUnauthorized {
+		t.Errorf("GET /admin/submissions without a token = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	if code, _ := adminRequest(t, server, http.MethodPost, "/admin/submissions/"+ignored.ID+"/ignore?reason=expired"); code != http.StatusOK {
+		t.Fatalf("POST ignore = %d, want %d", code, http.StatusOK)
+	}
+	if err := pipeline.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	posted := fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 1 || posted[0].LinkID != matched.Name {
+		t.Fatalf("Run() posted %+v, want a comment on %s only", posted, matched.Name)
+	}
+
+	code, body := adminRequest(t, server, http.MethodGet, "/admin/submissions?outcome=ignored")
+	var records []store.Record
+	if err := json.Unmarshal([]byte(body), &records); code != http.StatusOK || err != nil {
+		t.Fatalf("GET /admin/submissions = %d, %q, want a list of records", code, body)
+	}
+	if len(records) != 1 || records[0].SubmissionID != ignored.ID || records[0].Reason != "expired" {
+		t.Errorf("GET /admin/submissions?outcome=ignored = %+v, want the ignored submission", records)
+	}
+
+	if _, body := adminRequest(t, server, http.MethodGet, "/admin/submissions?outcome=commented"); !strings.Contains(body, `"driveIds":["1100"]`) {
+		t.Errorf("GET /admin/submissions?outcome=commented = %q, want the drives of the comment", body)
+	}
+
+	code, body = adminRequest(t, server, http.MethodGet, "/admin/preview?title=Solidigm+P44+Pro+2TB")
+	if code != http.StatusOK || !strings.Contains(body, `"driveId":"1100"`) || !strings.Contains(body, "Solidigm P44 Pro 2 TB") {
+		t.Errorf("GET /admin/preview = %d, %q, want the P44 Pro and its comment", code, body)
+	}
+	if code, _ := adminRequest(t, server, http.MethodGet, "/admin/preview"); code != http.StatusBadRequest {
+		t.Errorf("GET /admin/preview without a title = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// a reprocessed submission is commented on even without the rule's flair
+	if code, _ := adminRequest(t, server, http.MethodPost, "/admin/submissions/"+unflaired.ID+"/reprocess"); code != http.StatusAccepted {
+		t.Fatalf("POST reprocess = %d, want %d", code, http.StatusAccepted)
+	}
+	// a commented submission keeps its record and comment
+	commented, _, _ := processed.Get(matched.ID)
+	for _, action := range []string{"reprocess", "ignore"} {
+		if code, _ := adminRequest(t, server, http.MethodPost, "/admin/submissions/"+matched.ID+"/"+action); code != http.StatusConflict {
+			t.Errorf("POST %s of a commented submission = %d, want %d", action, code, http.StatusConflict)
+		}
+		record, _, _ := processed.Get(matched.ID)
+		if record.Outcome != store.OutcomeCommented || record.CommentName == "" || record.CommentName != commented.CommentName || !slices.Equal(record.DriveIDs, commented.DriveIDs) {
+			t.Errorf("POST %s of a commented submission stored %+v, want %+v", action, record, commented)
+		}
+	}
+	if err := pipeline.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if got := len(fake.CommentsBy("_SSD_BOT_")); got != 2 {
+		t.Errorf("Run() after reprocessing posted %d comments in total, want 2", got)
+	}
+	if code, _ := adminRequest(t, server, http.MethodPost, "/admin/submissions/"+other.ID+"/reprocess"); code != http.StatusUnprocessableEntity {
+		t.Errorf("POST reprocess of an unwatched subreddit = %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+	if code, _ := adminRequest(t, server, http.MethodPost, "/admin/submissions/missing/reprocess"); code != http.StatusNotFound {
+		t.Errorf("POST reprocess of a missing submission = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if code, body := adminRequest(t, server, http.MethodPost, "/admin/posting/pause"); code != http.StatusOK || !paused.Load() {
+		t.Errorf("POST pause = %d, %q, want posting paused", code, body)
+	}
+	if _, body := adminRequest(t, server, http.MethodGet, "/admin/posting"); !strings.Contains(body, `"paused":true`) {
+		t.Errorf("GET /admin/posting = %q, want paused", body)
+	}
+
+	// a submission polled while paused is commented on once resumed
+	held := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Corsair MP600 Mini 1TB - $60", LinkFlairText: "SSD"})
+	if err := pipeline.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if got := len(fake.CommentsBy("_SSD_BOT_")); got != 2 {
+		t.Errorf("Run() while paused posted %d comments in total, want 2", got)
+	}
+	if _, body := adminRequest(t, server, http.MethodGet, "/admin/submissions?outcome=queued"); !strings.Contains(body, held.ID) {
+		t.Errorf("GET /admin/submissions?outcome=queued = %q, want %s", body, held.ID)
+	}
+	if code, body := adminRequest(t, server, http.MethodPost, "/admin/posting/resume"); code != http.StatusOK || paused.Load() {
+		t.Errorf("POST resume = %d, %q, want posting resumed", code, body)
+	}
+	if err := pipeline.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if posted := fake.CommentsBy("_SSD_BOT_"); len(posted) != 3 || posted[len(posted)-1].LinkID != held.Name {
+		t.Errorf("Run() after resuming posted %+v, want a comment on %s", posted, held.Name)
+	}
+}
+
+func TestPausable(t *testing.T) {
+	var paused atomic.Bool
+	runs := 0
+	run := pausable(&paused, "test", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+
+	paused.Store(true)
+	run(context.Background())
+	if runs != 0 {
+		t.Errorf("pausable() ran %d times while paused, want 0", runs)
+	}
+	paused.Store(false)
+	run(context.Background())
+	if runs != 1 {
+		t.Errorf("pausable() ran %d times after resuming, want 1", runs)
+	}
+}
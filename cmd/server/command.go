@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aattwwss/ssd-bot-go/internal/bot"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+)
+
+// MAX_COMPARE_MODELS caps how many drives one compare command looks up.
+const MAX_COMPARE_MODELS = 4
+
+const commandUsage = `I understand these commands:
+
+* ` + "`lookup <model>`" + ` - the specs of a drive, e.g. ` + "`lookup WD SN850X 2TB`" + `
+* ` + "`compare <model> | <model>`" + ` - up to 4 drives side by side, e.g. ` + "`compare Samsung 990 Pro 2TB | WD SN850X 2TB`" + `
+* ` + "`variants <model>`" + ` - every capacity a drive is sold in, e.g. ` + "`variants Crucial P3 Plus`"
+
+var errUnknownCommand = errors.New("unknown command")
+
+// helpPattern finds messages asking for help without a command word.
+var helpPattern = regexp.MustCompile(`(?i)\bhelp\b`)
+
+// command is a lookup requested by private message.
+type command struct {
+	name   string
+	models []string
+}
+
+// parseCommand parses the first line of a message body, or the subject when
+// the body holds no command, e.g. "lookup WD SN850X 2TB".
+func parseCommand(subject, body string) (command, error) {
+	cmd, err := parseCommandLine(body)
+	if errors.Is(err, errUnknownCommand) {
+		return parseCommandLine(subject)
+	}
+	return cmd, err
+}
+
+func parseCommandLine(s string) (command, error) {
+	var line string
+	for _, l := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(l); line != "" {
+			break
+		}
+	}
+	name, rest, _ := strings.Cut(line, " ")
+	name = strings.ToLower(strings.TrimLeft(name, "!/"))
+	rest = strings.TrimSpace(rest)
+
+	switch name {
+	case "help":
+		return command{name: name}, nil
+	case "lookup", "variants":
+		if rest == "" {
+			return command{}, fmt.Errorf("%s needs a model, e.g. `%s Crucial P3 Plus 1TB`", name, name)
+		}
+		return command{name: name, models: []string{rest}}, nil
+	case "compare":
+		var models []string
+		for _, model := range strings.Split(rest, "|") {
+			if model = strings.TrimSpace(model); model != "" {
+				models = append(models, model)
+			}
+		}
+		if len(models) < 2 || len(models) > MAX_COMPARE_MODELS {
+			return command{}, fmt.Errorf("compare needs 2 to %d models separated by |, e.g. `compare Samsung 990 Pro 2TB | WD SN850X 2TB`", MAX_COMPARE_MODELS)
+		}
+		return command{name: name, models: models}, nil
+	}
+	return command{}, errUnknownCommand
+}
+
+// execute runs the command in a message and returns the text to answer with.
+// Invalid commands and messages asking for help are answered with the usage,
+// other messages, e.g. spam, with an empty text. Errors are only returned
+// when the lookup failed and should be retried.
+func (r *inboxResponder) execute(ctx context.Context, message reddit.Message) (string, error) {
+	cmd, err := parseCommand(message.Subject, message.Body)
+	if errors.Is(err, errUnknownCommand) {
+		if helpPattern.MatchString(message.Subject) || helpPattern.MatchString(message.Body) {
+			return commandUsage, nil
+		}
+		return "", nil
+	}
+	if err != nil {
+		return fmt.Sprintf("Sorry, %v.\n\n%s", err, commandUsage), nil
+	}
+
+	var found []ssd.SSD
+	for _, model := range cmd.models {
+		s, err := bot.FindSSD(ctx, r.matcher, model)
+		if err != nil {
+			return "", err
+		}
+		if s == nil {
+			return fmt.Sprintf("Sorry, I could not find %q in the [TechPowerUp SSD Database](%s).", model, ssd.TechPowerUpURL), nil
+		}
+		found = append(found, *s)
+	}
+
+	switch cmd.name {
+	case "lookup":
+		return found[0].ToMarkdown(), nil
+	case "compare":
+		return ssd.CompareMarkdown(found), nil
+	case "variants":
+		variants, err := findVariants(ctx, r.esRepo, found[0])
+		if err != nil {
+			return "", err
+		}
+		return ssd.VariantsMarkdown(variants), nil
+	}
+	return commandUsage, nil
+}
+
+// findVariants returns every capacity of the drive's model, the drive included.
+func findVariants(ctx context.Context, esRepo ssd.Repository, drive ssd.SSD) ([]ssd.SSD, error) {
+	candidates, err := esRepo.Search(ctx, strings.ToLower(drive.Manufacturer+" "+drive.Name))
+	if err != nil {
+		return nil, err
+	}
+	variants := []ssd.SSD{drive}
+	seen := map[string]bool{drive.DriveID: true}
+	for _, candidate := range candidates {
+		if seen[candidate.DriveID] || candidate.Manufacturer != drive.Manufacturer || candidate.Name != drive.Name {
+			continue
+		}
+		seen[candidate.DriveID] = true
+		variants = append(variants, candidate)
+	}
+	return variants, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+		want    command
+		wantErr bool
+	}{
+		{name: "lookup", subject: "ssd", body: "lookup WD SN850X 2TB", want: command{name: "lookup", models: []string{"WD SN850X 2TB"}}},
+		{name: "case and prefix", subject: "ssd", body: "\n  !Lookup  crucial p3 \nthanks", want: command{name: "lookup", models: []string{"crucial p3"}}},
+		{name: "compare", subject: "ssd", body: "compare Samsung 990 Pro 2TB | WD SN850X 2TB", want: command{name: "compare", models: []string{"Samsung 990 Pro 2TB", "WD SN850X 2TB"}}},
+		{name: "variants", subject: "ssd", body: "variants Crucial P3 Plus", want: command{name: "variants", models: []string{"Crucial P3 Plus"}}},
+		{name: "command in subject", subject: "lookup kingston nv2", body: "please", want: command{name: "lookup", models: []string{"kingston nv2"}}},
+		{name: "help", subject: "help", body: "help", want: command{name: "help"}},
+		{name: "lookup without model", subject: "ssd", body: "lookup", wantErr: true},
+		{name: "compare one model", subject: "ssd", body: "compare Samsung 990 Pro |", wantErr: true},
+		{name: "compare too many models", subject: "ssd", body: "compare a | b | c | d | e", wantErr: true},
+		{name: "unknown", subject: "hello", body: "what is the best ssd?", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCommand(tt.subject, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCommand() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseCommand("hello", "hi"); !errors.Is(err, errUnknownCommand) {
+		t.Errorf("parseCommand() error = %v, want %v", err, errUnknownCommand)
+	}
+}
+
+func TestInboxResponderCommands(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	p44Pro1TB := ssd.SSD{DriveID: "1099", Manufacturer: "Solidigm", Name: "P44 Pro", Capacity: "1 TB", URL: "https://www.techpowerup.com/ssd-specs/solidigm-p44-pro-1-tb.d1099"}
+	repo := ssdtest.NewRepository(append([]ssd.SSD{p44Pro1TB}, ssdtest.Drives...)...)
+	inbox := newInboxResponder(rc, repo, config.Config{Username: "_SSD_BOT_", AnswerMessages: true}, nil)
+
+	tests := []struct {
+		name     string
+		subject  string
+		body     string
+		want     []string
+		wantSubj string
+	}{
+		{name: "lookup", subject: "lookup", body: "lookup Corsair MP600 Mini", want: []string{"The Corsair MP600 Mini 1 TB"}, wantSubj: "re: lookup"},
+		{name: "compare", subject: "re: drives", body: "compare Corsair MP600 Mini | Solidigm P44 Pro 2TB", want: []string{"|Corsair MP600 Mini 1 TB|Solidigm P44 Pro 2 TB|"}, wantSubj: "re: drives"},
+		{name: "variants", subject: "variants", body: "variants Solidigm P44 Pro", want: []string{"comes in 2 variants", "|1 TB|", "|2 TB|"}, wantSubj: "re: variants"},
+		{name: "not found", subject: "lookup", body: "lookup Unknown Drive", want: []string{`could not find "Unknown Drive"`}, wantSubj: "re: lookup"},
+		{name: "invalid", subject: "compare", body: "compare Corsair MP600 Mini", want: []string{"compare needs 2 to 4 models", "I understand these commands"}, wantSubj: "re: compare"},
+		{name: "asking for help", subject: "hi", body: "Help, which ssd should I buy?", want: []string{"I understand these commands"}, wantSubj: "re: hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.AddMessage("alice", "_SSD_BOT_", tt.subject, tt.body)
+			if err := inbox.run(context.Background()); err != nil {
+				t.Fatalf("run() unexpected error = %v", err)
+			}
+			received := fake.MessagesTo("alice")
+			if len(received) == 0 {
+				t.Fatalf("run() sent no message to alice")
+			}
+			got := received[len(received)-1]
+			if got.Subject != tt.wantSubj {
+				t.Errorf("run() sent subject %q, want %q", got.Subject, tt.wantSubj)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got.Body, want) {
+					t.Errorf("run() sent %q, want it to contain %q", got.Body, want)
+				}
+			}
+		})
+	}
+
+	// messages without a command, e.g. spam, are read but not answered
+	fake.AddMessage("alice", "_SSD_BOT_", "Cheap followers", "Grow your account today!")
+	if err := inbox.run(context.Background()); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+	if got := len(fake.MessagesTo("alice")); got != len(tests) {
+		t.Errorf("run() sent %d messages in total, want %d", got, len(tests))
+	}
+	if unread := fake.Unread("_SSD_BOT_"); len(unread) != 0 {
+		t.Errorf("Unread() after run() = %+v, want every message marked as read", unread)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// READY_TIMEOUT bounds the checks of a /readyz request.
+	READY_TIMEOUT = 5 * time.Second
+	// SHUTDOWN_TIMEOUT is how long in-flight HTTP requests get on shutdown.
+	SHUTDOWN_TIMEOUT = 5 * time.Second
+)
+
+// readinessCheck is a dependency the bot needs to do its work, e.g. a valid
+// reddit token.
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// newHTTPHandler serves /healthz, answering as long as the process runs,
+// /readyz, answering 503 when one of the checks fails, and the Prometheus
+// metrics on /metrics. The admin API is served too when admin is not nil.
+func newHTTPHandler(checks []readinessCheck, admin *adminAPI) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), READY_TIMEOUT)
+		defer cancel()
+		var failed []string
+		for _, c := range checks {
+			if err := c.check(ctx); err != nil {
+				log.Warn().Msgf("Readiness check %s failed: %v", c.name, err)
+				failed = append(failed, fmt.Sprintf("%s: %v", c.name, err))
+			}
+		}
+		if len(failed) > 0 {
+			http.Error(w, strings.Join(failed, "\n"), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("GET /metrics", promhttp.Handler())
+	if admin != nil {
+		admin.register(mux)
+	}
+	return mux
+}
+
+// serveHTTP serves handler on addr until ctx is cancelled.
+func serveHTTP(ctx context.Context, addr string, handler http.Handler) {
+	server := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), SHUTDOWN_TIMEOUT)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error().Msgf("HTTP server shutdown error: %v", err)
+		}
+	}()
+	log.Info().Msgf("Serving health checks and metrics on %s", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Msgf("HTTP server error: %v", err)
+	}
+}
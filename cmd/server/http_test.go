@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/store"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+func get(t *testing.T, server *httptest.Server, path string) (int, string) {
+	t.Helper()
+	resp, err := server.Client().Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s unexpected error = %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestHTTPHandler(t *testing.T) {
+	var esErr error
+	server := httptest.NewServer(newHTTPHandler([]readinessCheck{
+		{name: "reddit", check: func(ctx context.Context) error { return nil }},
+		{name: "elasticsearch", check: func(ctx context.Context) error { return esErr }},
+	}, nil))
+	defer server.Close()
+
+	if code, _ := get(t, server, "/healthz"); code != http.StatusOK {
+		t.Errorf("GET /healthz = %d, want %d", code, http.StatusOK)
+	}
+	if code, _ := get(t, server, "/readyz"); code != http.StatusOK {
+		t.Errorf("GET /readyz = %d, want %d", code, http.StatusOK)
+	}
+	esErr = errors.New("connection refused")
+	if code, body := get(t, server, "/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "elasticsearch: connection refused") {
+		t.Errorf("GET /readyz = %d %q, want %d naming elasticsearch", code, body, http.StatusServiceUnavailable)
+	}
+	if code, _ := get(t, server, "/healthz"); code != http.StatusOK {
+		t.Errorf("GET /healthz with elasticsearch down = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc, err := reddit.NewRedditClient("id", "secret", "_SSD_BOT_", "password", "", 0, false, append(fake.Options(), reddit.WithTransport(timedTransport{next: http.DefaultTransport}))...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	repo := timedRepository{ssdtest.NewRepository(ssdtest.Drives...)}
+	cfg := config.Config{OverrideOldBot: true}
+	rule := config.SubredditRule{Name: "metricsales", Flairs: []string{"SSD"}}
+
+	fake.AddSubmission(reddit.Submission{Subreddit: "metricsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+	fake.AddSubmission(reddit.Submission{Subreddit: "metricsales", Title: "[SSD] Unknown brand 1TB", LinkFlairText: "SSD"})
+	fake.AddSubmission(reddit.Submission{Subreddit: "metricsales", Title: "[GPU] RTX 4090", LinkFlairText: "GPU"})
+
+	if err := newPipeline(cfg, rule, rc, repo, store.NewMemoryStore(), nil).Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+
+	server := httptest.NewServer(newHTTPHandler(nil, nil))
+	defer server.Close()
+	_, body := get(t, server, "/metrics")
+	for _, want := range []string{
+		`ssdbot_submissions_seen_total{subreddit="metricsales"} 3`,
+		`ssdbot_submissions_filtered_total{subreddit="metricsales"} 1`,
+		`ssdbot_submissions_matched_total{subreddit="metricsales"} 1`,
+		`ssdbot_submissions_unmatched_total{subreddit="metricsales"} 1`,
+		`ssdbot_comments_posted_total{subreddit="metricsales"} 1`,
+		`ssdbot_es_request_duration_seconds_count{operation="search"}`,
+		`ssdbot_reddit_request_duration_seconds_count{code="200",method="GET"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET /metrics is missing %s", want)
+		}
+	}
+}
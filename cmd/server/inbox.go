@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aattwwss/ssd-bot-go/internal/bot"
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/feedback"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MAX_SUMMON_LENGTH caps the model text taken from a mention.
+	MAX_SUMMON_LENGTH = 100
+	// MAX_SUBJECT_LENGTH is the longest subject reddit accepts for a message.
+	MAX_SUBJECT_LENGTH = 100
+)
+
+// inboxResponder answers the unread items of the bot's inbox: comments
+// summoning it with "u/<bot> <model>" get a reply with the specs of the drive
+// found for the model text, private messages are run as commands and replies
+// to the bot's comments are stored as feedback.
+type inboxResponder struct {
+	rc       *reddit.Client
+	esRepo   ssd.Repository
+	matcher  bot.Matcher
+	username string
+	pattern  *regexp.Regexp
+	mentions bool
+	commands bool
+	// ignored are accounts whose messages are never answered, so bots cannot
+	// summon each other in a loop
+	ignored []string
+	// feedback is nil when corrections are not collected
+	feedback feedback.Store
+	// replied holds the messages already answered, in case marking them as
+	// read failed and they show up again
+	replied map[string]bool
+}
+
+func newInboxResponder(rc *reddit.Client, esRepo ssd.Repository, cfg config.Config, feedbackStore feedback.Store) *inboxResponder {
+	return &inboxResponder{
+		rc:       rc,
+		esRepo:   esRepo,
+		matcher:  bot.NewESMatcher(esRepo, cfg.MatchThreshold),
+		username: cfg.Username,
+		pattern:  regexp.MustCompile(`(?im)(?:^|[^\w/])/?u/` + regexp.QuoteMeta(cfg.Username) + `\b[ \t:,]*(.*)$`),
+		mentions: cfg.AnswerMentions,
+		commands: cfg.AnswerMessages,
+		ignored:  append([]string{"AutoModerator"}, cfg.CompetingBots...),
+		feedback: feedbackStore,
+		replied:  map[string]bool{},
+	}
+}
+
+// run answers the unread messages of the bot. Every message that was
+// handled, answered or not, is marked as read so it is not looked at again.
+// Messages that failed with a temporary error stay unread and are retried on
+// the next run.
+func (r *inboxResponder) run(ctx context.Context) error {
+	messages, err := r.rc.GetUnreadMessages(ctx, PAGE_SIZE)
+	if err != nil {
+		return err
+	}
+	var read []string
+	defer func() {
+		// still mark the answered messages as read when shutting down
+		if err := r.rc.MarkRead(context.WithoutCancel(ctx), read...); err != nil {
+			log.Error().Msgf("Error marking %d messages as read: %v", len(read), err)
+		}
+	}()
+
+	// answer in the order the messages were received
+	slices.Reverse(messages)
+	for _, message := range messages {
+		if reason := r.ignoreReason(message); reason != "" {
+			log.Debug().Msgf("Ignoring message %s from %s: %s", message.Name, message.Author, reason)
+			read = append(read, message.Name)
+			continue
+		}
+
+		handled, err := r.handle(ctx, message)
+		if handled {
+			read = append(read, message.Name)
+			r.replied[message.Name] = true
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ignoreReason returns why a message should not be answered, or an empty
+// string when it should.
+func (r *inboxResponder) ignoreReason(message reddit.Message) string {
+	switch {
+	case message.Author == "":
+		return "not sent by a user"
+	case strings.EqualFold(message.Author, r.username):
+		return "written by the bot"
+	case r.replied[message.Name]:
+		return "already replied"
+	}
+	for _, author := range r.ignored {
+		if strings.EqualFold(message.Author, author) {
+			return "written by an ignored bot"
+		}
+	}
+	return ""
+}
+
+// handle answers a message. It reports whether the message was handled and
+// must not be looked at again.
+func (r *inboxResponder) handle(ctx context.Context, message reddit.Message) (bool, error) {
+	if !message.WasComment {
+		if !r.commands {
+			log.Debug().Msgf("Ignoring message %s from %s: answering messages is disabled", message.Name, message.Author)
+			return true, nil
+		}
+		return r.answerCommand(ctx, message)
+	}
+	if query, ok := parseSummon(r.pattern, message.Body); ok && r.mentions {
+		return r.answerMention(ctx, message, query)
+	}
+	if r.feedback != nil && message.Type == reddit.MessageCommentReply {
+		return r.collectFeedback(ctx, message)
+	}
+	log.Debug().Msgf("Ignoring comment %s from %s", message.Name, message.Author)
+	return true, nil
+}
+
+// answerMention replies to a comment summoning the bot to look up query.
+func (r *inboxResponder) answerMention(ctx context.Context, message reddit.Message, query string) (bool, error) {
+	log.Info().Msgf("Summoned by %s in %s: %s", message.Author, message.Context, query)
+	found, err := bot.FindSSD(ctx, r.matcher, query)
+	if err != nil {
+		log.Error().Msgf("Error searching for ssd: %v", err)
+		return false, nil
+	}
+	if found == nil {
+		log.Info().Msgf("SSD not found in database: %s", query)
+		return true, nil
+	}
+	comment, err := r.rc.Reply(ctx, message.Name, found.ToMarkdown())
+	if errors.Is(err, reddit.ErrThreadLocked) || errors.Is(err, reddit.ErrNotFound) || errors.Is(err, reddit.ErrForbidden) {
+		log.Warn().Msgf("Cannot reply to %s: %v", message.Name, err)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	log.Info().Msgf("Reply submitted as %s for: %v", comment.Name, found)
+	return true, pause(ctx)
+}
+
+// answerCommand runs the command in a private message and sends the result
+// back by private message.
+func (r *inboxResponder) answerCommand(ctx context.Context, message reddit.Message) (bool, error) {
+	log.Info().Msgf("Message from %s: %s", message.Author, message.Subject)
+	text, err := r.execute(ctx, message)
+	if err != nil {
+		log.Error().Msgf("Error running command from %s: %v", message.Author, err)
+		return false, nil
+	}
+	if text == "" {
+		log.Info().Msgf("Ignoring message from %s: no command", message.Author)
+		return true, nil
+	}
+
+	subject := message.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "re: " + subject
+	}
+	subject = truncate(subject, MAX_SUBJECT_LENGTH)
+	err = r.rc.SendMessage(ctx, message.Author, subject, text)
+	var submitErr *reddit.SubmitError
+	if errors.As(err, &submitErr) && !errors.Is(err, reddit.ErrRateLimited) {
+		// e.g. the user deleted their account or does not accept messages
+		log.Warn().Msgf("Cannot send message to %s: %v", message.Author, err)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	log.Info().Msgf("Message sent to %s", message.Author)
+	return true, pause(ctx)
+}
+
+// collectFeedback stores a reply to one of the bot's comments as feedback on
+// the drive it picked, when the reply uses the !wrong or !correct syntax or
+// comes from the author of the submission. On a comment showing several
+// drives the feedback is about the one the reply names.
+func (r *inboxResponder) collectFeedback(ctx context.Context, message reddit.Message) (bool, error) {
+	verdict, suggested, ok := feedback.Parse(message.Body)
+
+	parents, err := r.rc.GetCommentsByName(ctx, message.ParentID)
+	if err != nil {
+		return false, err
+	}
+	if len(parents) == 0 {
+		log.Info().Msgf("Ignoring reply %s: parent comment %s not found", message.Name, message.ParentID)
+		return true, nil
+	}
+	driveIDs := ssd.ParseDriveIDs(parents[0].Body)
+	if len(driveIDs) == 0 {
+		// e.g. a reply to an answered private message command
+		log.Debug().Msgf("Ignoring reply %s: %s does not link a drive", message.Name, message.ParentID)
+		return true, nil
+	}
+	submissions, err := r.rc.GetSubmissionsByName(ctx, parents[0].LinkID)
+	if err != nil {
+		return false, err
+	}
+	if len(submissions) == 0 {
+		log.Info().Msgf("Ignoring reply %s: submission %s not found", message.Name, parents[0].LinkID)
+		return true, nil
+	}
+	submission := submissions[0]
+	isSubmitter := strings.EqualFold(submission.Author, message.Author)
+	if !ok && !isSubmitter {
+		log.Debug().Msgf("Ignoring reply %s from %s: not feedback", message.Name, message.Author)
+		return true, nil
+	}
+	if !ok {
+		verdict = feedback.VerdictComment
+	}
+
+	record := feedback.Record{
+		SubmissionID:   submission.ID,
+		Title:          submission.Title,
+		DriveIDs:       driveIDs,
+		Verdict:        verdict,
+		SuggestedModel: suggested,
+		Author:         message.Author,
+		IsSubmitter:    isSubmitter,
+		CommentName:    message.Name,
+		Body:           message.Body,
+		CreatedAt:      message.Created(),
+	}
+	var drives []ssd.SSD
+	for _, id := range driveIDs {
+		drive, err := r.esRepo.FindById(ctx, id)
+		if err != nil {
+			log.Error().Msgf("Error finding drive %s: %v", id, err)
+			return false, nil
+		}
+		if drive != nil {
+			drives = append(drives, *drive)
+		}
+	}
+	switch {
+	case len(driveIDs) == 1:
+		record.DriveID = driveIDs[0]
+		if len(drives) == 1 {
+			record.Model = drives[0].Manufacturer + " " + drives[0].Name
+		}
+	default:
+		if drive, ok := namedDrive(message.Body, drives); ok {
+			record.DriveID, record.Model = drive.DriveID, drive.Manufacturer+" "+drive.Name
+		} else {
+			log.Info().Msgf("Reply %s names none of the drives %v", message.Name, driveIDs)
+		}
+	}
+	if err := r.feedback.Add(record); err != nil {
+		log.Error().Msgf("Error storing feedback %s: %v", message.Name, err)
+		return false, nil
+	}
+	log.Info().Msgf("Feedback from %s on %s: %s %s", message.Author, submission.Title, verdict, suggested)
+	return true, nil
+}
+
+// namedDrive returns the one of drives whose name a reply mentions, the
+// longest name winning, so "P3 Plus" is not taken for the "P3". It returns
+// false when the reply names none or a model shown more than once, e.g. in two
+// capacities.
+func namedDrive(body string, drives []ssd.SSD) (ssd.SSD, bool) {
+	body = strings.ToLower(body)
+	nameOf := func(drive ssd.SSD) string {
+		return strings.ToLower(strings.TrimSpace(strings.ReplaceAll(drive.Name, "(w/ Heatsink)", "")))
+	}
+	var named []ssd.SSD
+	for _, drive := range drives {
+		if regexp.MustCompile(`\b` + regexp.QuoteMeta(nameOf(drive)) + `\b`).MatchString(body) {
+			named = append(named, drive)
+		}
+	}
+	if len(named) == 0 {
+		return ssd.SSD{}, false
+	}
+	slices.SortStableFunc(named, func(a, b ssd.SSD) int {
+		return len(nameOf(b)) - len(nameOf(a))
+	})
+	if len(named) > 1 && nameOf(named[0]) == nameOf(named[1]) {
+		return ssd.SSD{}, false
+	}
+	return named[0], true
+}
+
+// pause waits between two submissions to prevent getting rejected.
+func pause(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(COMMENT_RATE_LIMIT):
+		return nil
+	}
+}
+
+// parseSummon returns the text following the first mention matched by
+// pattern, up to the end of its line.
+func parseSummon(pattern *regexp.Regexp, body string) (string, bool) {
+	// the markdown editor escapes underscores, e.g. u/\_SSD\_BOT\_
+	match := pattern.FindStringSubmatch(strings.ReplaceAll(body, `\`, ""))
+	if match == nil {
+		return "", false
+	}
+	query := truncate(strings.TrimSpace(match[1]), MAX_SUMMON_LENGTH)
+	return query, query != ""
+}
+
+// truncate cuts s to at most n characters, never splitting a multi-byte one.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/feedback"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+var mentionsConfig = config.Config{Username: "_SSD_BOT_", AnswerMentions: true, CompetingBots: []string{"SSDBot"}}
+
+func TestInboxResponderMentions(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	inbox := newInboxResponder(rc, repo, mentionsConfig, nil)
+
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapc", Title: "Which SSD for my build?"})
+	summon := fake.AddComment(submission.ID, "alice", `What controller does it use? u/\_SSD\_BOT\_ Solidigm P44 Pro 2TB`)
+	fake.AddComment(submission.ID, "bob", "thanks u/_SSD_BOT_")
+	fake.AddComment(submission.ID, "SSDBot", "u/_SSD_BOT_ Corsair MP600 Mini")
+	fake.AddComment(submission.ID, "carol", "u/_SSD_BOT_ some unknown drive")
+
+	if err := inbox.run(context.Background()); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+	posted := fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 1 {
+		t.Fatalf("run() posted %d replies, want 1", len(posted))
+	}
+	if posted[0].ParentID != summon.Name || !strings.Contains(posted[0].Body, "Solidigm P44 Pro 2 TB") {
+		t.Errorf("run() posted %+v, want the P44 Pro specs in reply to %s", posted[0], summon.Name)
+	}
+	if unread := fake.Unread("_SSD_BOT_"); len(unread) != 0 {
+		t.Errorf("Unread() after run() = %+v, want every message marked as read", unread)
+	}
+
+	// a follow up summon in reply to the bot is answered, the bot's own reply is not
+	followUp := fake.AddReply(posted[0].Name, "alice", "u/_SSD_BOT_ corsair mp600 mini")
+	if err := inbox.run(context.Background()); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+	if err := inbox.run(context.Background()); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+	posted = fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 2 || posted[1].ParentID != followUp.Name {
+		t.Errorf("run() posted %+v, want a single reply to %s", posted, followUp.Name)
+	}
+}
+
+func TestInboxResponderMentionsRetriesFailedReply(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	inbox := newInboxResponder(rc, ssdtest.NewRepository(ssdtest.Drives...), mentionsConfig, nil)
+
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapc", Title: "Which SSD for my build?"})
+	summon := fake.AddComment(submission.ID, "alice", "u/_SSD_BOT_ Solidigm P44 Pro")
+	fake.InjectAPIError("/api/comment", "RATELIMIT", "you are doing that too much. try again in 1 minute.")
+
+	if err := inbox.run(context.Background()); err == nil {
+		t.Fatalf("run() error = nil, want the rate limit error")
+	}
+	if unread := fake.Unread("_SSD_BOT_"); len(unread) != 1 || unread[0].Name != summon.Name {
+		t.Fatalf("Unread() after a failed reply = %+v, want %s", unread, summon.Name)
+	}
+	if err := inbox.run(context.Background()); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+	if posted := fake.CommentsBy("_SSD_BOT_"); len(posted) != 1 || posted[0].ParentID != summon.Name {
+		t.Errorf("run() posted %+v, want a reply to %s", posted, summon.Name)
+	}
+}
+
+func TestParseSummon(t *testing.T) {
+	pattern := newInboxResponder(nil, nil, mentionsConfig, nil).pattern
+	tests := []struct {
+		name   string
+		body   string
+		want   string
+		wantOk bool
+	}{
+		{name: "model after mention", body: "u/_SSD_BOT_ WD SN850X 2TB", want: "WD SN850X 2TB", wantOk: true},
+		{name: "leading slash and colon", body: "/u/_SSD_BOT_: Samsung 990 Pro", want: "Samsung 990 Pro", wantOk: true},
+		{name: "escaped underscores", body: `hey u/\_SSD\_BOT\_ crucial p3 plus`, want: "crucial p3 plus", wantOk: true},
+		{name: "case insensitive", body: "u/_ssd_bot_ kingston nv2", want: "kingston nv2", wantOk: true},
+		{name: "only rest of line", body: "u/_SSD_BOT_ crucial t500\nthanks!", want: "crucial t500", wantOk: true},
+		{name: "mention without model", body: "thanks u/_SSD_BOT_", want: "", wantOk: false},
+		{name: "other user", body: "u/_SSD_BOT_2 crucial p3", want: "", wantOk: false},
+		{name: "no mention", body: "crucial p3", want: "", wantOk: false},
+		{name: "long model cut by characters", body: "u/_SSD_BOT_ " + strings.Repeat("é", 150), want: strings.Repeat("é", MAX_SUMMON_LENGTH), wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseSummon(pattern, tt.body)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("parseSummon() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestInboxResponderFeedback(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	store := feedback.NewFileStore(filepath.Join(t.TempDir(), "feedback.jsonl"))
+	inbox := newInboxResponder(rc, repo, mentionsConfig, store)
+
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", Author: "op"})
+	botComment, err := rc.SubmitComment(context.Background(), submission.ID, ssdtest.Drives[1].ToMarkdown())
+	if err != nil {
+		t.Fatalf("SubmitComment() unexpected error = %v", err)
+	}
+	wrong := fake.AddReply(botComment.Name, "alice", "!wrong Solidigm P41 Plus")
+	correct := fake.AddReply(botComment.Name, "bob", "!correct")
+	fromOP := fake.AddReply(botComment.Name, "op", "wrong drive, this is the P41 Plus")
+	fake.AddReply(botComment.Name, "carol", "good bot")
+
+	if err := inbox.run(context.Background()); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+	records, err := store.All()
+	if err != nil {
+		t.Fatalf("All() unexpected error = %v", err)
+	}
+	want := []struct {
+		comment     string
+		verdict     feedback.Verdict
+		suggested   string
+		isSubmitter bool
+	}{
+		{comment: wrong.Name, verdict: feedback.VerdictWrong, suggested: "Solidigm P41 Plus"},
+		{comment: correct.Name, verdict: feedback.VerdictCorrect},
+		{comment: fromOP.Name, verdict: feedback.VerdictComment, isSubmitter: true},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("run() stored %+v, want %d records", records, len(want))
+	}
+	for i, w := range want {
+		got := records[i]
+		if got.CommentName != w.comment || got.Verdict != w.verdict || got.SuggestedModel != w.suggested || got.IsSubmitter != w.isSubmitter {
+			t.Errorf("record %d = %+v, want %+v", i, got, w)
+		}
+		if got.SubmissionID != submission.ID || got.Title != submission.Title || got.DriveID != "1100" || got.Model != "Solidigm P44 Pro" {
+			t.Errorf("record %d = %+v, want the submission and the P44 Pro picked by the bot", i, got)
+		}
+	}
+
+	// feedback on a comment showing several drives is about the one it names
+	many, err := rc.SubmitComment(context.Background(), submission.ID, ssd.MultiMarkdownRegion(ssdtest.Drives, ""))
+	if err != nil {
+		t.Fatalf("SubmitComment() unexpected error = %v", err)
+	}
+	named := fake.AddReply(many.Name, "alice", "!correct, at least the Corsair MP600 Mini")
+	unnamed := fake.AddReply(many.Name, "bob", "!correct")
+	if err := inbox.run(context.Background()); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+	if records, err = store.All(); err != nil || len(records) != len(want)+2 {
+		t.Fatalf("All() = %+v, %v, want %d records", records, err, len(want)+2)
+	}
+	for i, w := range []struct {
+		comment string
+		driveID string
+		model   string
+	}{
+		{comment: named.Name, driveID: "1461", model: "Corsair MP600 Mini"},
+		{comment: unnamed.Name},
+	} {
+		got := records[len(want)+i]
+		if got.CommentName != w.comment || got.DriveID != w.driveID || got.Model != w.model || !slices.Equal(got.DriveIDs, []string{"1461", "1100"}) {
+			t.Errorf("record %d = %+v, want drive %q of both", len(want)+i, got, w.driveID)
+		}
+	}
+	if unread := fake.Unread("_SSD_BOT_"); len(unread) != 0 {
+		t.Errorf("Unread() after run() = %+v, want every reply marked as read", unread)
+	}
+	if posted := fake.CommentsBy("_SSD_BOT_"); len(posted) != 2 {
+		t.Errorf("run() posted %+v, want no replies to feedback", posted[2:])
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	esRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ssdbot_es_request_duration_seconds",
+		Help:    "Latency of Elasticsearch requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+	redditRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ssdbot_reddit_request_duration_seconds",
+		Help:    "Latency of Reddit requests, including the token requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	lastSuccessfulRun = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ssdbot_last_successful_run_timestamp_seconds",
+		Help: "Unix time the job last finished without an error.",
+	}, []string{"job"})
+)
+
+// timedTransport records the latency of every Reddit request.
+type timedTransport struct {
+	next http.RoundTripper
+}
+
+func (t timedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	redditRequestDuration.WithLabelValues(req.Method, code).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// timedRepository records the latency of the Elasticsearch requests of the
+// wrapped repository.
+type timedRepository struct {
+	ssd.Repository
+}
+
+func (r timedRepository) FindById(ctx context.Context, id string) (*ssd.SSD, error) {
+	defer observeSince(esRequestDuration.WithLabelValues("findById"), time.Now())
+	return r.Repository.FindById(ctx, id)
+}
+
+func (r timedRepository) SearchBasic(ctx context.Context, s string) ([]ssd.SSDBasic, error) {
+	defer observeSince(esRequestDuration.WithLabelValues("searchBasic"), time.Now())
+	return r.Repository.SearchBasic(ctx, s)
+}
+
+func (r timedRepository) Search(ctx context.Context, s string) ([]ssd.SSD, error) {
+	defer observeSince(esRequestDuration.WithLabelValues("search"), time.Now())
+	return r.Repository.Search(ctx, s)
+}
+
+func (r timedRepository) SearchScored(ctx context.Context, s string) ([]ssd.ScoredSSD, error) {
+	defer observeSince(esRequestDuration.WithLabelValues("search"), time.Now())
+	return ssd.SearchScored(ctx, r.Repository, s)
+}
+
+func observeSince(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// job is work the bot repeats every interval, e.g. polling a subreddit.
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	next     time.Time
+}
+
+// schedule runs the jobs until ctx is cancelled, each one again once its
+// interval passed since it last finished. Jobs run one at a time, so the
+// comments they submit through the shared reddit client stay rate limited
+// and in order.
+func schedule(ctx context.Context, jobs []*job) {
+	if len(jobs) == 0 {
+		return
+	}
+	for {
+		next := jobs[0]
+		for _, j := range jobs[1:] {
+			if j.next.Before(next.next) {
+				next = j
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next.next)):
+		}
+
+		log.Debug().Msgf("Running %s", next.name)
+		err := next.run(ctx)
+		if err == nil {
+			lastSuccessfulRun.WithLabelValues(next.name).SetToCurrentTime()
+		} else if ctx.Err() == nil {
+			logRunError(fmt.Errorf("%s: %w", next.name, err))
+		}
+		next.next = time.Now().Add(next.interval)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var running atomic.Int32
+	var overlapped atomic.Bool
+	runs := map[string]int{}
+	newJob := func(name string, interval time.Duration, err error) *job {
+		return &job{name: name, interval: interval, run: func(ctx context.Context) error {
+			if running.Add(1) > 1 {
+				overlapped.Store(true)
+			}
+			defer running.Add(-1)
+			runs[name]++
+			time.Sleep(5 * time.Millisecond)
+			return err
+		}}
+	}
+	jobs := []*job{
+		newJob("fast", 20*time.Millisecond, nil),
+		newJob("slow", time.Hour, errors.New("failed")),
+	}
+
+	schedule(ctx, jobs)
+
+	if overlapped.Load() {
+		t.Errorf("schedule() ran jobs concurrently")
+	}
+	if runs["slow"] != 1 {
+		t.Errorf("schedule() ran the slow job %d times, want 1", runs["slow"])
+	}
+	if runs["fast"] < 3 {
+		t.Errorf("schedule() ran the fast job %d times, want at least 3", runs["fast"])
+	}
+}
@@ -3,17 +3,21 @@ package main
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
-	"regexp"
-	"sort"
-	"strconv"
-	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/aattwwss/ssd-bot-go/internal/bot"
 	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/curation"
+	"github.com/aattwwss/ssd-bot-go/internal/feedback"
+	"github.com/aattwwss/ssd-bot-go/internal/redditclient"
+	"github.com/aattwwss/ssd-bot-go/internal/store"
 	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
 
 	"github.com/aattwwss/ssd-bot-go/elasticutil"
@@ -24,9 +28,10 @@ import (
 )
 
 const (
-	LINK_PREFIX       = "t3_"
-	ES_INDEX          = "ssd-index"
-	POLL_INTERVAL     = 15 * time.Minute
+	ES_INDEX           = "ssd-index"
+	POLL_INTERVAL      = 15 * time.Minute
+	PAGE_SIZE          = 25
+	BOT_COMMENTS_LIMIT = 100
 	COMMENT_RATE_LIMIT = 1 * time.Second
 )
 
@@ -40,8 +45,16 @@ func main() {
 	if err := env.Parse(&cfg); err != nil {
 		log.Fatal().Msgf("Parse env error: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal().Msgf("Config error: %v", err)
+	}
 
-	rc, err := reddit.NewRedditClient(cfg.ClientId, cfg.ClientSecret, cfg.Username, cfg.Password, cfg.Token, cfg.ExpireTimeMilli, cfg.OverrideOldBot)
+	redditOpts, err := redditclient.Options(cfg)
+	if err != nil {
+		log.Fatal().Msgf("Reddit config error: %v", err)
+	}
+	redditOpts = append(redditOpts, reddit.WithTransport(timedTransport{next: http.DefaultTransport}))
+	rc, err := reddit.NewRedditClient(cfg.ClientId, cfg.ClientSecret, cfg.Username, cfg.Password, cfg.Token, cfg.ExpireTimeMilli, cfg.OverrideOldBot, redditOpts...)
 	if err != nil {
 		log.Fatal().Msgf("Init reddit client error: %v", err)
 	}
@@ -49,7 +62,48 @@ func main() {
 	if err != nil {
 		log.Fatal().Msgf("Init elasticsearch client error: %v", err)
 	}
-	esRepo := ssd.NewEsRepository(es, ES_INDEX)
+	var esRepo ssd.Repository = timedRepository{ssd.NewEsRepository(es, ES_INDEX)}
+	rules, err := cfg.SubredditRules()
+	if err != nil {
+		log.Fatal().Msgf("Subreddit rules error: %v", err)
+	}
+	var processed store.Store = store.NewMemoryStore()
+	var dryRun *bot.DryRunLog
+	if cfg.DryRun {
+		// keep the store of the live bot untouched and never post anything
+		out := os.Stdout
+		if cfg.DryRunFile != "" {
+			out, err = os.OpenFile(cfg.DryRunFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				log.Fatal().Msgf("Open dry run file error: %v", err)
+			}
+			defer out.Close()
+		}
+		dryRun = bot.NewDryRunLog(out)
+		log.Info().Msg("Dry run, comments are written out instead of posted")
+	} else if cfg.StoreFile != "" {
+		boltStore, err := store.OpenBoltStore(cfg.StoreFile)
+		if err != nil {
+			log.Fatal().Msgf("Open store error: %v", err)
+		}
+		defer boltStore.Close()
+		processed = boltStore
+	}
+	var inbox *inboxResponder
+	if cfg.AnswerMentions || cfg.AnswerMessages || cfg.FeedbackFile != "" {
+		var feedbackStore feedback.Store
+		if cfg.FeedbackFile != "" {
+			feedbackStore = feedback.NewFileStore(cfg.FeedbackFile)
+		}
+		if cfg.DryRun {
+			// the inbox is left to the live bot
+			log.Warn().Msg("Not reading the inbox during a dry run, ignoring ANSWER_MENTIONS, ANSWER_MESSAGES and FEEDBACK_FILE")
+		} else if rc.HasUserContext() {
+			inbox = newInboxResponder(rc, esRepo, cfg, feedbackStore)
+		} else {
+			log.Warn().Msg("Reading the inbox needs a user context, ignoring ANSWER_MENTIONS, ANSWER_MESSAGES and FEEDBACK_FILE")
+		}
+	}
 
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -65,164 +119,103 @@ func main() {
 		cancel()
 	}()
 
-	// doTest(esRepo)
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msg("Shutdown requested, exiting...")
-			return
-		default:
-			err = run(ctx, cfg, rc, esRepo)
-			if err != nil {
-				log.Error().Msgf("Error during run: %v", err)
-			}
-			time.Sleep(POLL_INTERVAL)
+	// paused holds back the comments and replies until resumed through the
+	// admin API
+	var paused atomic.Bool
+	var jobs []*job
+	var pipelines []*bot.Pipeline
+	for _, rule := range rules {
+		if rule.Disabled {
+			log.Info().Msgf("Not watching r/%s, it is disabled", rule.Name)
+			continue
 		}
+		pipeline := newPipeline(cfg, rule, rc, esRepo, processed, dryRun)
+		pipeline.Paused = &paused
+		pipelines = append(pipelines, pipeline)
+		name := "r/" + rule.Name
+		jobs = append(jobs, &job{
+			name:     name,
+			interval: rule.PollInterval.Duration,
+			run: func(ctx context.Context) error {
+				err := pipeline.Run(ctx)
+				if limit := rc.RateLimit(); limit.Known() {
+					log.Info().Msgf("Reddit rate limit: %.0f remaining, %d used, resets at %v", limit.Remaining, limit.Used, limit.Reset.Format(time.TimeOnly))
+				}
+				return err
+			},
+		})
 	}
-}
-
-func run(ctx context.Context, cfg config.Config, rc *reddit.Client, esRepo *ssd.EsRepository) error {
-	log.Info().Msg("Start searching...")
-	newSubmissions, err := rc.GetNewSubmissions(cfg.Subreddit, 25)
-	if err != nil {
-		return err
-	}
-
-	botComments, err := rc.GetUserNewestComments(25)
-	if err != nil {
-		return err
-	}
-
-	botCommentsMap := map[string]bool{}
-	for _, comment := range botComments {
-		linkId := strings.TrimPrefix(comment.LinkID, LINK_PREFIX)
-		botCommentsMap[linkId] = true
+	if inbox != nil {
+		jobs = append(jobs, &job{name: "inbox", interval: POLL_INTERVAL, run: pausable(&paused, "inbox", inbox.run)})
 	}
 
-	for _, submission := range newSubmissions {
-		if !strings.Contains(strings.ToUpper(submission.LinkFlairText), "SSD") {
-			continue
-		}
-		if !cfg.OverrideOldBot {
-			// do not comment if another bot already commented
-			botToCheck := "SSDBot"
-			botCommented := rc.IsCommentedByUser(submission.ID, botToCheck)
-			if botCommented {
-				log.Info().Msgf("%s already commented on this submission: %s", botToCheck, submission.Title)
-				continue
-			}
+	if cfg.HTTPAddr != "" {
+		var admin *adminAPI
+		if cfg.AdminToken != "" {
+			admin = newAdminAPI(cfg, processed, bot.NewESMatcher(esRepo, cfg.MatchThreshold), rc, pipelines, &paused)
+		} else {
+			log.Info().Msg("ADMIN_TOKEN is empty, not serving the admin API")
 		}
-
-		_, ok := botCommentsMap[submission.ID]
-		if ok {
-			log.Info().Msgf("This bot already commented on this submission: %s", submission.Title)
-			continue
-		}
-
-		log.Info().Msgf("Found submission: %s", submission.Title)
-		title := cleanTitle(submission.Title)
-		ssdList, err := esRepo.Search(ctx, title)
-		if err != nil {
-			log.Error().Msgf("Error searching for ssd: %v", err)
-			continue
-		}
-		ssdList = sanityCheck(title, ssdList)
-		if len(ssdList) == 0 {
-			log.Info().Msgf("SSD not found in database: %s", submission.Title)
-			continue
-		}
-		sort.Slice(ssdList, func(i, j int) bool {
-			iName := strings.ReplaceAll(ssdList[i].Name, "(w/ Heatsink)", "")
-			jName := strings.ReplaceAll(ssdList[j].Name, "(w/ Heatsink)", "")
-			if len(iName) == len(jName) {
-				numI, errI := strconv.Atoi(ssdList[i].DriveID)
-				numJ, errJ := strconv.Atoi(ssdList[j].DriveID)
-				// If both are valid integers, compare numerically
-				if errI == nil && errJ == nil {
-					return numI > numJ
-				}
-				// If only one is valid, prefer the valid one
-				if errI == nil {
-					return true
+		go serveHTTP(ctx, cfg.HTTPAddr, newHTTPHandler([]readinessCheck{
+			{name: "reddit", check: rc.RefreshToken},
+			{name: "elasticsearch", check: func(ctx context.Context) error {
+				res, err := es.Ping(es.Ping.WithContext(ctx))
+				if err != nil {
+					return err
 				}
-				if errJ == nil {
-					return false
+				defer res.Body.Close()
+				if res.IsError() {
+					return fmt.Errorf("ping: %s", res.Status())
 				}
-				// If neither is valid, fall back to string comparison
-				return ssdList[i].DriveID > ssdList[j].DriveID
-			}
-			return len(iName) > len(jName)
-		})
-		log.Info().Msgf("Final sorted filtered list %v", ssdList)
-		found := ssdList[0]
-		err = rc.SubmitComment(submission.ID, found.ToMarkdown())
-		if err != nil {
-			return err
-		}
-		log.Info().Msgf("Post submitted for: %v", found)
-		//rate limit submission of post to prevent getting rejected
-		time.Sleep(COMMENT_RATE_LIMIT)
+				return nil
+			}},
+		}, admin))
 	}
-	log.Info().Msg("End searching...")
-	return nil
-}
 
-// rules to ensure no false positives
-// 1. Manufacturer must be in the search query
-// 2. Name must be in the search query (without the heatsink part)
-func sanityCheck(searchQuery string, ssds []ssd.SSD) []ssd.SSD {
-	var filtered []ssd.SSD
-	for _, ssd := range ssds {
-		log.Debug().Msgf("checking %s %s", ssd.Manufacturer, ssd.Name)
-		if !strings.Contains(strings.ToLower(strings.ReplaceAll(searchQuery, " ", "")), strings.ToLower(strings.ReplaceAll(ssd.Manufacturer, " ", ""))) {
-			log.Debug().Msgf("skipping %s %s because manufacturer is missing from search query", ssd.Manufacturer, ssd.Name)
-			continue
-		}
-		ssdName := strings.ReplaceAll(ssd.Name, "(w/ Heatsink)", "")
-		words := strings.Split(ssdName, " ")
-		hasMissingWord := false
-		for _, word := range words {
-			if !strings.Contains(strings.ToLower(strings.ReplaceAll(searchQuery, " ", "")), strings.ToLower(strings.ReplaceAll(word, " ", ""))) {
-				hasMissingWord = true
-				log.Debug().Msgf("skipping %s %s because %s is missing from search query", ssd.Manufacturer, ssd.Name, word)
-				break
-			}
-		}
-		if !hasMissingWord {
-			log.Debug().Msgf("adding %s %s to filtered list", ssd.Manufacturer, ssd.Name)
-			filtered = append(filtered, ssd)
-		}
-	}
-	return filtered
+	// doTest(esRepo)
+	schedule(ctx, jobs)
+	log.Info().Msg("Shutdown requested, exiting...")
 }
 
-func cleanTitle(s string) string {
-	s = strings.ToLower(s)
-	s = regexp.MustCompile(`\[[^\]]+\]`).ReplaceAllString(s, "")
-
-	// Remove common strings that don't help with SSD identification
-	stringsToRemove := []string{"ssd", "m2", "m.2", "nvme", "pcie", "gen", "amazon"}
-	for _, toReplace := range stringsToRemove {
-		s = strings.ReplaceAll(s, toReplace, "")
+// newPipeline wires a subreddit rule to reddit and the drive database. The
+// pipeline only comments when rc has a user context and dryRun is nil.
+func newPipeline(cfg config.Config, rule config.SubredditRule, rc *reddit.Client, esRepo ssd.Repository, processed store.Store, dryRun *bot.DryRunLog) *bot.Pipeline {
+	p := &bot.Pipeline{
+		Config:          cfg,
+		Rule:            rule,
+		Source:          reddit.NewSubmissionPoller(rc, rule.Name, PAGE_SIZE, cfg.PollMaxPages),
+		Lookup:          rc,
+		Ledger:          bot.NewRedditLedger(rc, BOT_COMMENTS_LIMIT),
+		Matcher:         bot.NewESMatcher(esRepo, cfg.MatchThreshold),
+		Renderer:        bot.MarkdownRenderer{},
+		Processed:       processed,
+		DryRun:          dryRun,
+		CommentInterval: COMMENT_RATE_LIMIT,
 	}
-
-	// Build the result string with expansions
-	var builder strings.Builder
-	builder.WriteString(s)
-
-	// Add expansions for known abbreviations
-	stringsToReplace := map[string]string{
-		" wd":         " western digital",
-		"team group":  " teamgroup",
-		"spatium":     " msi spatium",
-		"sn850x":      " western digital sn850x",
+	if rc.HasUserContext() {
+		p.Commenter = rc
 	}
-	for k, v := range stringsToReplace {
-		if strings.Contains(s, k) {
-			builder.WriteString(v)
-		}
+	if cfg.MissesFile != "" {
+		p.Misses = curation.NewFileStore(cfg.MissesFile)
+	}
+	return p
+}
+
+// logRunError logs a failed run with a hint on what the reddit error means.
+func logRunError(err error) {
+	switch {
+	case errors.Is(err, reddit.ErrUnauthorized):
+		log.Error().Msgf("Reddit rejected the access token, it will be refreshed on the next run: %v", err)
+	case errors.Is(err, reddit.ErrForbidden):
+		log.Error().Msgf("Reddit denied access, check whether the bot is banned: %v", err)
+	case errors.Is(err, reddit.ErrRateLimited):
+		retryAfter, _ := reddit.RetryAfter(err)
+		log.Error().Msgf("Reddit rate limited the bot, retry after %v: %v", retryAfter, err)
+	case errors.Is(err, reddit.ErrServer):
+		log.Error().Msgf("Reddit is unavailable, retrying on the next run: %v", err)
+	default:
+		log.Error().Msgf("Error during run: %v", err)
 	}
-	return builder.String()
 }
 
 func doTest(esRepo *ssd.EsRepository) error {
@@ -253,7 +246,7 @@ func doTest(esRepo *ssd.EsRepository) error {
 
 	// Print each record
 	for _, record := range records {
-		ssds, err := esRepo.Search(context.Background(), cleanTitle(record[1]))
+		ssds, err := esRepo.Search(context.Background(), bot.CleanTitle(record[1]))
 		if err != nil {
 			log.Error().Err(err).Msgf("Error searching for SSD: %s", record[1])
 			continue
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/config/configtest"
+	"github.com/aattwwss/ssd-bot-go/internal/store"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+func TestRun(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", CompetingBots: []string{"SSDBot"}}
+	pipeline := newPipeline(cfg, configtest.Rule, rc, repo, store.NewMemoryStore(), nil)
+
+	matched := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD - M.2"})
+	fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[GPU] RTX 4090", LinkFlairText: "GPU"})
+	fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Unknown brand 1TB", LinkFlairText: "SSD"})
+	competing := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Corsair MP600 Mini 1TB", LinkFlairText: "SSD"})
+	fake.AddComment(competing.ID, "SSDBot", "specs")
+
+	if err := pipeline.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+
+	posted := fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 1 {
+		t.Fatalf("Run() posted %d comments, want 1", len(posted))
+	}
+	if posted[0].LinkID != matched.Name || !strings.Contains(posted[0].Body, "Solidigm P44 Pro 2 TB") {
+		t.Errorf("Run() posted %+v, want the P44 Pro specs on %s", posted[0], matched.Name)
+	}
+
+	// a second run must not comment on the same submission again
+	if err := pipeline.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if got := len(fake.CommentsBy("_SSD_BOT_")); got != 1 {
+		t.Errorf("second Run() posted %d comments in total, want 1", got)
+	}
+}
@@ -0,0 +1,408 @@
+// Package bot is the pipeline commenting drive specs on sale submissions:
+// poll the new submissions of a subreddit, skip the ineligible ones and those
+// already commented on, match the title to a drive and comment its specs.
+// Every step is an interface, so the pipeline runs against fakes in tests and
+// against reddit and Elasticsearch in cmd/server.
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/curation"
+	"github.com/aattwwss/ssd-bot-go/internal/store"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/rs/zerolog/log"
+)
+
+// SubmissionSource polls the new submissions of a subreddit, e.g. a
+// *reddit.SubmissionPoller.
+type SubmissionSource interface {
+	Poll(ctx context.Context) ([]reddit.Submission, error)
+	// Newest and SetNewest get and set where the next Poll starts, so a
+	// failed run can have its submissions polled again.
+	Newest() string
+	SetNewest(fullname string)
+}
+
+// CommentLedger tells which submissions were already commented on, by this
+// bot or by a competing one.
+type CommentLedger interface {
+	// BotCommented returns the IDs of the submissions the bot recently
+	// commented on.
+	BotCommented(ctx context.Context) (map[string]bool, error)
+	// BotCommentedOn tells whether the bot commented on a submission, however
+	// long ago.
+	BotCommentedOn(ctx context.Context, submissionId string) (bool, error)
+	// CommentedBy returns the first of authors that commented on the
+	// submission, or an empty string when none did.
+	CommentedBy(ctx context.Context, submissionId string, authors ...string) (string, error)
+}
+
+// SubmissionLookup fetches submissions by fullname, e.g. a *reddit.Client.
+type SubmissionLookup interface {
+	GetSubmissionsByName(ctx context.Context, fullnames ...string) ([]reddit.Submission, error)
+}
+
+// Matcher finds the drives a text like a submission title names.
+type Matcher interface {
+	// Match returns the drives found for text and how sure it is about each,
+	// best match first.
+	Match(ctx context.Context, text string) (MatchResult, error)
+}
+
+// Renderer renders the comment about a drive.
+type Renderer interface {
+	Render(drive ssd.SSD, region string) string
+	// RenderNearTie renders the comment about a title that is about as likely
+	// one of several drives, best match first.
+	RenderNearTie(drives []ssd.SSD, region string) string
+	// RenderMany renders the comment about a title mentioning several drives.
+	RenderMany(drives []ssd.SSD, region string) string
+}
+
+// Commenter comments on submissions, e.g. a *reddit.Client with a user
+// context.
+type Commenter interface {
+	SubmitComment(ctx context.Context, submissionId, text string) (*reddit.SubmissionComment, error)
+}
+
+// MarkdownRenderer renders the specs of a drive as reddit Markdown.
+type MarkdownRenderer struct{}
+
+func (MarkdownRenderer) Render(drive ssd.SSD, region string) string {
+	return drive.ToMarkdownRegion(region)
+}
+
+// RenderNearTie renders the drives side by side.
+func (MarkdownRenderer) RenderNearTie(drives []ssd.SSD, region string) string {
+	return nearTieIntro + ssd.CompareMarkdownRegion(drives, region)
+}
+
+// nearTieIntro starts the comments of MarkdownRenderer.RenderNearTie.
+const nearTieIntro = "This is possibly one of these drives:\n\n"
+
+// IsNearTie reports whether a comment was rendered by
+// MarkdownRenderer.RenderNearTie.
+func IsNearTie(body string) bool {
+	return strings.HasPrefix(body, nearTieIntro)
+}
+
+func (MarkdownRenderer) RenderMany(drives []ssd.SSD, region string) string {
+	return ssd.MultiMarkdownRegion(drives, region)
+}
+
+// Pipeline comments the specs of the drives in the new submissions of one
+// subreddit.
+type Pipeline struct {
+	Config config.Config
+	Rule   config.SubredditRule
+
+	Source SubmissionSource
+	// Lookup fetches the queued submissions again.
+	Lookup   SubmissionLookup
+	Ledger   CommentLedger
+	Matcher  Matcher
+	Renderer Renderer
+	// Commenter is nil for a read only client, the drives found are then
+	// only logged.
+	Commenter Commenter
+	// Processed holds the submissions already looked at.
+	Processed store.Store
+	// Misses, when set, queues the submissions no drive was found for with
+	// the search candidates.
+	Misses curation.Store
+	// DryRun, when set, gets the comments instead of the Commenter and
+	// nothing is stored.
+	DryRun *DryRunLog
+	// CommentInterval is the pause after each comment, so reddit does not
+	// reject the next one.
+	CommentInterval time.Duration
+	// Paused, when set and true, holds the comments back: the submissions
+	// are stored as queued and commented on by the first run after resuming.
+	Paused *atomic.Bool
+}
+
+// Run polls the new submissions once and comments on those a drive was found
+// for, then processes the queued submissions of the subreddit. Submissions in
+// the processed store are not looked at again unless queued, every other
+// submission is stored once the pipeline is done with it. When Run fails in a
+// way worth retrying, the submissions are polled or stay queued for next time.
+func (p *Pipeline) Run(ctx context.Context) error {
+	log.Info().Msgf("Start searching r/%s...", p.Rule.Name)
+	previous := p.Source.Newest()
+	newSubmissions, err := p.Source.Poll(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Found %d new submissions", len(newSubmissions))
+	submissionsSeen.WithLabelValues(p.Rule.Name).Add(float64(len(newSubmissions)))
+
+	botComments, err := p.Ledger.BotCommented(ctx)
+	if err != nil {
+		p.Source.SetNewest(previous)
+		return err
+	}
+
+	for _, submission := range newSubmissions {
+		if err := p.process(ctx, submission, botComments, false); err != nil {
+			// fetch these submissions again on the next run
+			p.Source.SetNewest(previous)
+			return err
+		}
+	}
+	queued, err := p.queued(ctx)
+	if err != nil {
+		return err
+	}
+	for _, submission := range queued {
+		if err := p.process(ctx, submission, botComments, true); err != nil {
+			return err
+		}
+	}
+	log.Info().Msgf("End searching r/%s...", p.Rule.Name)
+	return nil
+}
+
+// queued fetches the submissions of the subreddit stored as queued, e.g.
+// reprocessed by an admin or held back while paused. Nothing is fetched while
+// paused, the submissions would only be queued again.
+func (p *Pipeline) queued(ctx context.Context) ([]reddit.Submission, error) {
+	if p.Lookup == nil || p.Paused != nil && p.Paused.Load() {
+		return nil, nil
+	}
+	records, err := p.Processed.Recent(-1)
+	if err != nil {
+		return nil, err
+	}
+	queued := map[string]store.Record{}
+	var fullnames []string
+	for _, record := range records {
+		if record.Outcome == store.OutcomeQueued && strings.EqualFold(record.Subreddit, p.Rule.Name) {
+			queued[record.SubmissionID] = record
+			fullnames = append(fullnames, "t3_"+record.SubmissionID)
+		}
+	}
+	if len(fullnames) == 0 {
+		return nil, nil
+	}
+	var submissions []reddit.Submission
+	for chunk := range slices.Chunk(fullnames, reddit.MaxInfoNames) {
+		found, err := p.Lookup.GetSubmissionsByName(ctx, chunk...)
+		if err != nil {
+			// a submission is only taken as deleted after a complete lookup
+			return nil, err
+		}
+		submissions = append(submissions, found...)
+	}
+	for _, submission := range submissions {
+		delete(queued, submission.ID)
+	}
+	for _, record := range queued {
+		log.Info().Msgf("Queued submission %s was deleted: %s", record.SubmissionID, record.Title)
+		record.Outcome, record.Reason = store.OutcomeSkipped, "submission not found"
+		p.put(record)
+	}
+	return submissions, nil
+}
+
+// process looks at one submission, a forced one even when it does not match
+// the rule. Errors are only returned when the run must stop and be retried.
+func (p *Pipeline) process(ctx context.Context, submission reddit.Submission, botComments map[string]bool, forced bool) error {
+	if !forced && !p.Rule.Matches(submission) {
+		submissionsFiltered.WithLabelValues(p.Rule.Name).Inc()
+		return nil
+	}
+	record, ok, err := p.Processed.Get(submission.ID)
+	if err != nil {
+		return err
+	}
+	if ok && record.Outcome != store.OutcomeQueued {
+		log.Info().Msgf("Already processed submission (%s): %s", record.Outcome, submission.Title)
+		if record.Outcome == store.OutcomeCommented {
+			submissionsAlreadyCommented.WithLabelValues(p.Rule.Name).Inc()
+		}
+		return nil
+	}
+	record = store.Record{SubmissionID: submission.ID, Subreddit: submission.Subreddit, Title: submission.Title}
+
+	if reason := skipReason(submission, time.Now(), p.Config.MaxSubmissionAge); reason != "" {
+		log.Info().Msgf("Skipping submission %s: %s", submission.Title, reason)
+		record.Outcome, record.Reason = store.OutcomeSkipped, reason
+		p.put(record)
+		return nil
+	}
+	if p.Config.SkipCompetingBots(p.Rule) {
+		// do not comment if another bot already commented
+		competitor, err := p.Ledger.CommentedBy(ctx, submission.ID, p.Config.CompetingBots...)
+		if errors.Is(err, reddit.ErrNotFound) || errors.Is(err, reddit.ErrForbidden) {
+			log.Warn().Msgf("Cannot read comments of submission %s: %v", submission.Title, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if competitor != "" {
+			log.Info().Msgf("%s already commented on this submission: %s", competitor, submission.Title)
+			record.Outcome, record.Reason = store.OutcomeSkipped, competitor+" already commented"
+			p.put(record)
+			return nil
+		}
+	}
+
+	commented := botComments[submission.ID]
+	if !commented && forced {
+		// a queued submission may be older than the recent bot comments
+		commented, err = p.Ledger.BotCommentedOn(ctx, submission.ID)
+		if errors.Is(err, reddit.ErrNotFound) || errors.Is(err, reddit.ErrForbidden) {
+			log.Warn().Msgf("Cannot read comments of submission %s: %v", submission.Title, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if commented {
+		log.Info().Msgf("This bot already commented on this submission: %s", submission.Title)
+		submissionsAlreadyCommented.WithLabelValues(p.Rule.Name).Inc()
+		record.Outcome = store.OutcomeCommented
+		p.put(record)
+		return nil
+	}
+
+	log.Info().Msgf("Found submission: %s", submission.Title)
+	match, err := MatchTitle(ctx, p.Matcher, submission.Title)
+	if err != nil {
+		log.Error().Msgf("Error searching for ssd: %v", err)
+		return nil
+	}
+	if len(match.Drives) > 0 {
+		submissionsMatched.WithLabelValues(p.Rule.Name).Inc()
+	} else {
+		submissionsUnmatched.WithLabelValues(p.Rule.Name).Inc()
+	}
+	if p.DryRun != nil {
+		markdown := match.Render(p.Renderer, p.Rule.Region, p.Config.MatchNearTie)
+		if err := p.DryRun.Write(submission, match.Merged(), markdown); err != nil {
+			return err
+		}
+		log.Info().Msgf("Dry run, not commenting %d drives on: %s", len(match.Drives), submission.Title)
+		return nil
+	}
+	if len(match.Drives) == 0 {
+		log.Info().Msgf("SSD not found in database: %s", submission.Title)
+		record.Outcome = store.OutcomeNoMatch
+		p.put(record)
+		p.addMiss(submission, match.Merged())
+		return nil
+	}
+	if p.Commenter == nil {
+		log.Info().Msgf("Read only client, not commenting %v on: %s", match.Drives, submission.Title)
+		return nil
+	}
+	if p.Paused != nil && p.Paused.Load() {
+		log.Info().Msgf("Posting is paused, queueing %v for: %s", match.Drives, submission.Title)
+		record.Outcome, record.Reason = store.OutcomeQueued, "posting paused"
+		p.put(record)
+		return nil
+	}
+	// the drives the comment shows
+	drives := match.Drives
+	if nearTies := match.NearTies(p.Config.MatchNearTie); nearTies != nil {
+		log.Info().Msgf("Near tie of %d drives, commenting them all on: %s", len(nearTies), submission.Title)
+		drives = nearTies
+	}
+	markdown := match.Render(p.Renderer, p.Rule.Region, p.Config.MatchNearTie)
+	comment, err := p.Commenter.SubmitComment(ctx, submission.ID, markdown)
+	if errors.Is(err, reddit.ErrThreadLocked) || errors.Is(err, reddit.ErrNotFound) || errors.Is(err, reddit.ErrForbidden) {
+		log.Warn().Msgf("Cannot comment on submission %s: %v", submission.Title, err)
+		commentsFailed.WithLabelValues(p.Rule.Name).Inc()
+		record.Outcome, record.Reason = store.OutcomeSkipped, err.Error()
+		p.put(record)
+		return nil
+	}
+	if err != nil {
+		commentsFailed.WithLabelValues(p.Rule.Name).Inc()
+		return err
+	}
+	log.Info().Msgf("Post submitted as %s for: %v", comment.Name, match.Drives)
+	commentsPosted.WithLabelValues(p.Rule.Name).Inc()
+	var driveIDs []string
+	for _, drive := range drives {
+		driveIDs = append(driveIDs, drive.DriveID)
+	}
+	record.Outcome, record.DriveID, record.DriveIDs, record.CommentName = store.OutcomeCommented, driveIDs[0], driveIDs, comment.Name
+	p.put(record)
+	//rate limit submission of post to prevent getting rejected
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.CommentInterval):
+	}
+	return nil
+}
+
+// put stores a processed submission. Failing to store it only means the
+// submission is looked at again after a restart, so the error is logged.
+func (p *Pipeline) put(record store.Record) {
+	if err := p.Processed.Put(record); err != nil {
+		log.Error().Msgf("Error storing processed submission: %v", err)
+	}
+}
+
+// addMiss queues a submission no drive was found for. Like put, a failure
+// is only logged.
+func (p *Pipeline) addMiss(submission reddit.Submission, result MatchResult) {
+	if p.Misses == nil {
+		return
+	}
+	miss := curation.Miss{
+		SubmissionID: submission.ID,
+		Subreddit:    submission.Subreddit,
+		Title:        submission.Title,
+		CleanedTitle: result.Query,
+		Candidates:   []curation.Candidate{},
+		CreatedAt:    time.Now(),
+	}
+	for _, c := range result.Candidates {
+		miss.Candidates = append(miss.Candidates, curation.Candidate{
+			DriveID:      c.Drive.DriveID,
+			Manufacturer: c.Drive.Manufacturer,
+			Name:         c.Drive.Name,
+			Capacity:     c.Drive.Capacity,
+			Score:        c.Score,
+			Confidence:   c.Confidence,
+			Rejected:     c.Rejected,
+		})
+	}
+	if err := p.Misses.Add(miss); err != nil {
+		log.Error().Msgf("Error queueing missed submission: %v", err)
+	}
+}
+
+// skipReason returns why the bot should not comment on a submission, or an
+// empty string when it is eligible. A maxAge of zero disables the age check.
+func skipReason(submission reddit.Submission, now time.Time, maxAge time.Duration) string {
+	switch {
+	case submission.Locked:
+		return "submission is locked"
+	case submission.Archived:
+		return "submission is archived"
+	case submission.IsRemoved():
+		return fmt.Sprintf("submission was removed (%s)", submission.RemovedByCategory)
+	case submission.Stickied:
+		return "submission is stickied"
+	case submission.Over18:
+		return "submission is marked NSFW"
+	case maxAge > 0 && submission.CreatedUTC > 0 && now.Sub(submission.Created()) > maxAge:
+		return fmt.Sprintf("submission is older than %v", maxAge)
+	}
+	return ""
+}
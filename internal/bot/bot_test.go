@@ -0,0 +1,695 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/internal/config/configtest"
+	"github.com/aattwwss/ssd-bot-go/internal/curation"
+	"github.com/aattwwss/ssd-bot-go/internal/store"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+// newTestPipeline wires a pipeline to the fake reddit behind rc, the way
+// cmd/server does.
+func newTestPipeline(cfg config.Config, rule config.SubredditRule, rc *reddit.Client, repo ssd.Repository) *Pipeline {
+	p := &Pipeline{
+		Config:    cfg,
+		Rule:      rule,
+		Source:    reddit.NewSubmissionPoller(rc, rule.Name, 25, 4),
+		Ledger:    NewRedditLedger(rc, 100),
+		Matcher:   NewESMatcher(repo, cfg.MatchThreshold),
+		Renderer:  MarkdownRenderer{},
+		Processed: store.NewMemoryStore(),
+	}
+	if rc.HasUserContext() {
+		p.Commenter = rc
+	}
+	return p
+}
+
+// fakeSource serves a fixed list of submissions, newest first, like a
+// poller that starts over when rewound.
+type fakeSource struct {
+	submissions []reddit.Submission
+	newest      string
+}
+
+func (f *fakeSource) Poll(ctx context.Context) ([]reddit.Submission, error) {
+	var res []reddit.Submission
+	for _, s := range f.submissions {
+		if s.Name == f.newest {
+			break
+		}
+		res = append(res, s)
+	}
+	if len(f.submissions) > 0 {
+		f.newest = f.submissions[0].Name
+	}
+	return res, nil
+}
+
+func (f *fakeSource) Newest() string            { return f.newest }
+func (f *fakeSource) SetNewest(fullname string) { f.newest = fullname }
+
+// fakeLedger knows which submissions the bot and the competing bots
+// commented on. The older comments of the bot are not recent enough for
+// BotCommented.
+type fakeLedger struct {
+	bot         map[string]bool
+	older       map[string]bool
+	competitors map[string]string
+}
+
+func (f *fakeLedger) BotCommented(ctx context.Context) (map[string]bool, error) {
+	return f.bot, nil
+}
+
+func (f *fakeLedger) BotCommentedOn(ctx context.Context, submissionId string) (bool, error) {
+	return f.bot[submissionId] || f.older[submissionId], nil
+}
+
+func (f *fakeLedger) CommentedBy(ctx context.Context, submissionId string, authors ...string) (string, error) {
+	return f.competitors[submissionId], nil
+}
+
+// fakeLookup serves submissions by fullname, like reddit only the first
+// reddit.MaxInfoNames of a request.
+type fakeLookup []reddit.Submission
+
+func (f fakeLookup) GetSubmissionsByName(ctx context.Context, fullnames ...string) ([]reddit.Submission, error) {
+	fullnames = fullnames[:min(len(fullnames), reddit.MaxInfoNames)]
+	var res []reddit.Submission
+	for _, s := range f {
+		if slices.Contains(fullnames, s.Name) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// failingLookup fails every request after the first ok ones.
+type failingLookup struct {
+	fakeLookup
+	ok int
+}
+
+func (f *failingLookup) GetSubmissionsByName(ctx context.Context, fullnames ...string) ([]reddit.Submission, error) {
+	if f.ok == 0 {
+		return nil, errors.New("reddit is down")
+	}
+	f.ok--
+	return f.fakeLookup.GetSubmissionsByName(ctx, fullnames...)
+}
+
+// fakeMatcher finds the drives listed for a text, all of them with full
+// confidence.
+type fakeMatcher map[string][]ssd.SSD
+
+func (f fakeMatcher) Match(ctx context.Context, text string) (MatchResult, error) {
+	result := MatchResult{Query: text, Threshold: DefaultThreshold}
+	for _, drive := range f[text] {
+		result.Candidates = append(result.Candidates, Candidate{Drive: drive, Confidence: 1})
+	}
+	return result, nil
+}
+
+// fakeCommenter records the comments, failing with err when set.
+type fakeCommenter struct {
+	comments map[string]string
+	err      error
+}
+
+func (f *fakeCommenter) SubmitComment(ctx context.Context, submissionId, text string) (*reddit.SubmissionComment, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.comments[submissionId] = text
+	return &reddit.SubmissionComment{Name: "t1_" + submissionId}, nil
+}
+
+func TestRunWithFakes(t *testing.T) {
+	submissions := []reddit.Submission{
+		{ID: "matched", Name: "t3_matched", Title: "[SSD] Solidigm P44 Pro 2TB", LinkFlairText: "SSD"},
+		{ID: "gpu", Name: "t3_gpu", Title: "[GPU] RTX 4090", LinkFlairText: "GPU"},
+		{ID: "unknown", Name: "t3_unknown", Title: "[SSD] Unknown brand 1TB", LinkFlairText: "SSD"},
+		{ID: "locked", Name: "t3_locked", Title: "[SSD] Solidigm P44 Pro 2TB", LinkFlairText: "SSD", Locked: true},
+		{ID: "competing", Name: "t3_competing", Title: "[SSD] Corsair MP600 Mini 1TB", LinkFlairText: "SSD"},
+		{ID: "commented", Name: "t3_commented", Title: "[SSD] Corsair MP600 Mini 1TB", LinkFlairText: "SSD"},
+	}
+	commenter := &fakeCommenter{comments: map[string]string{}, err: reddit.ErrServer}
+	p := &Pipeline{
+		Config:    config.Config{CompetingBots: []string{"SSDBot"}},
+		Rule:      configtest.Rule,
+		Source:    &fakeSource{submissions: submissions},
+		Ledger:    &fakeLedger{bot: map[string]bool{"commented": true}, competitors: map[string]string{"competing": "SSDBot"}},
+		Matcher:   fakeMatcher{"[SSD] Solidigm P44 Pro 2TB": ssdtest.Drives[1:], "[SSD] Corsair MP600 Mini 1TB": ssdtest.Drives[:1]},
+		Renderer:  MarkdownRenderer{},
+		Commenter: commenter,
+		Processed: store.NewMemoryStore(),
+	}
+
+	// a failed comment is retried on the next run
+	if err := p.Run(context.Background()); !errors.Is(err, reddit.ErrServer) {
+		t.Fatalf("Run() error = %v, want %v", err, reddit.ErrServer)
+	}
+	commenter.err = nil
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if len(commenter.comments) != 1 || !strings.Contains(commenter.comments["matched"], "Solidigm P44 Pro 2 TB") {
+		t.Errorf("Run() commented %v, want the P44 Pro specs on matched only", commenter.comments)
+	}
+
+	want := map[string]store.Outcome{
+		"matched":   store.OutcomeCommented,
+		"unknown":   store.OutcomeNoMatch,
+		"locked":    store.OutcomeSkipped,
+		"competing": store.OutcomeSkipped,
+		"commented": store.OutcomeCommented,
+	}
+	for id, outcome := range want {
+		if record, _, _ := p.Processed.Get(id); record.Outcome != outcome {
+			t.Errorf("stored %s as %q, want %q", id, record.Outcome, outcome)
+		}
+	}
+	if _, ok, _ := p.Processed.Get("gpu"); ok {
+		t.Errorf("stored gpu, want submissions not matching the rule left out")
+	}
+}
+
+func TestRunQueued(t *testing.T) {
+	submissions := []reddit.Submission{
+		{ID: "unflaired", Name: "t3_unflaired", Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB"},
+		{ID: "old", Name: "t3_old", Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB", LinkFlairText: "SSD"},
+	}
+	commenter := &fakeCommenter{comments: map[string]string{}}
+	p := &Pipeline{
+		Rule:      configtest.Rule,
+		Source:    &fakeSource{},
+		Lookup:    fakeLookup(submissions),
+		Ledger:    &fakeLedger{bot: map[string]bool{}, older: map[string]bool{"old": true}},
+		Matcher:   fakeMatcher{"[SSD] Solidigm P44 Pro 2TB": ssdtest.Drives[1:]},
+		Renderer:  MarkdownRenderer{},
+		Commenter: commenter,
+		Processed: store.NewMemoryStore(),
+	}
+
+	// queued submissions skip the rule, but not the bot's older comments
+	for _, id := range []string{"unflaired", "old", "deleted"} {
+		p.Processed.Put(store.Record{SubmissionID: id, Subreddit: "BuildAPCSales", Outcome: store.OutcomeQueued})
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if len(commenter.comments) != 1 || commenter.comments["unflaired"] == "" {
+		t.Errorf("Run() commented %v, want unflaired only", commenter.comments)
+	}
+	want := map[string]store.Outcome{
+		"unflaired": store.OutcomeCommented,
+		"old":       store.OutcomeCommented,
+		"deleted":   store.OutcomeSkipped,
+	}
+	for id, outcome := range want {
+		if record, _, _ := p.Processed.Get(id); record.Outcome != outcome {
+			t.Errorf("stored %s as %q, want %q", id, record.Outcome, outcome)
+		}
+	}
+
+	// queued submissions are processed once
+	delete(commenter.comments, "unflaired")
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if len(commenter.comments) != 0 {
+		t.Errorf("second Run() commented %v, want nothing", commenter.comments)
+	}
+}
+
+func TestRunQueuedMany(t *testing.T) {
+	var submissions []reddit.Submission
+	commenter := &fakeCommenter{comments: map[string]string{}}
+	p := &Pipeline{
+		Rule:      configtest.Rule,
+		Source:    &fakeSource{},
+		Ledger:    &fakeLedger{bot: map[string]bool{}},
+		Matcher:   fakeMatcher{"[SSD] Solidigm P44 Pro 2TB": ssdtest.Drives[1:]},
+		Renderer:  MarkdownRenderer{},
+		Commenter: commenter,
+		Processed: store.NewMemoryStore(),
+	}
+	for i := 0; i < 2*reddit.MaxInfoNames+50; i++ {
+		id := fmt.Sprintf("s%d", i)
+		submissions = append(submissions, reddit.Submission{ID: id, Name: "t3_" + id, Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB"})
+		p.Processed.Put(store.Record{SubmissionID: id, Subreddit: "buildapcsales", Outcome: store.OutcomeQueued})
+	}
+	p.Processed.Put(store.Record{SubmissionID: "deleted", Subreddit: "buildapcsales", Outcome: store.OutcomeQueued})
+
+	// a failed lookup takes none of the submissions as deleted
+	p.Lookup = &failingLookup{fakeLookup: submissions, ok: 2}
+	if err := p.Run(context.Background()); err == nil {
+		t.Fatal("Run() expected error when the lookup fails")
+	}
+	records, _ := p.Processed.Recent(-1)
+	for _, record := range records {
+		if record.Outcome != store.OutcomeQueued {
+			t.Fatalf("stored %s as %q after a failed lookup, want %q", record.SubmissionID, record.Outcome, store.OutcomeQueued)
+		}
+	}
+
+	p.Lookup = fakeLookup(submissions)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if len(commenter.comments) != len(submissions) {
+		t.Errorf("Run() commented %d submissions, want %d", len(commenter.comments), len(submissions))
+	}
+	if record, _, _ := p.Processed.Get("deleted"); record.Outcome != store.OutcomeSkipped {
+		t.Errorf("stored deleted as %q, want %q", record.Outcome, store.OutcomeSkipped)
+	}
+}
+
+func TestRunPaused(t *testing.T) {
+	submissions := []reddit.Submission{
+		{ID: "matched", Name: "t3_matched", Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB", LinkFlairText: "SSD"},
+		{ID: "unknown", Name: "t3_unknown", Subreddit: "buildapcsales", Title: "[SSD] Unknown brand 1TB", LinkFlairText: "SSD"},
+	}
+	commenter := &fakeCommenter{comments: map[string]string{}}
+	var paused atomic.Bool
+	p := &Pipeline{
+		Rule:      configtest.Rule,
+		Source:    &fakeSource{submissions: submissions},
+		Lookup:    fakeLookup(submissions),
+		Ledger:    &fakeLedger{bot: map[string]bool{}},
+		Matcher:   fakeMatcher{"[SSD] Solidigm P44 Pro 2TB": ssdtest.Drives[1:]},
+		Renderer:  MarkdownRenderer{},
+		Commenter: commenter,
+		Processed: store.NewMemoryStore(),
+		Paused:    &paused,
+	}
+
+	// while paused the submissions are still polled and matched, only the
+	// comments wait
+	paused.Store(true)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if len(commenter.comments) != 0 {
+		t.Errorf("Run() while paused commented %v, want nothing", commenter.comments)
+	}
+	if record, _, _ := p.Processed.Get("matched"); record.Outcome != store.OutcomeQueued {
+		t.Errorf("stored matched as %q, want %q", record.Outcome, store.OutcomeQueued)
+	}
+	if record, _, _ := p.Processed.Get("unknown"); record.Outcome != store.OutcomeNoMatch {
+		t.Errorf("stored unknown as %q, want %q", record.Outcome, store.OutcomeNoMatch)
+	}
+
+	paused.Store(false)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if len(commenter.comments) != 1 || commenter.comments["matched"] == "" {
+		t.Errorf("Run() after resuming commented %v, want matched only", commenter.comments)
+	}
+}
+
+func TestRunQueuesMisses(t *testing.T) {
+	submissions := []reddit.Submission{
+		{ID: "matched", Name: "t3_matched", Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB", LinkFlairText: "SSD"},
+		{ID: "missed", Name: "t3_missed", Subreddit: "buildapcsales", Title: "[SSD] Solidigm P41 Plus 1TB", LinkFlairText: "SSD"},
+	}
+	misses := curation.NewFileStore(filepath.Join(t.TempDir(), "misses.jsonl"))
+	p := &Pipeline{
+		Rule:      configtest.Rule,
+		Source:    &fakeSource{submissions: submissions},
+		Ledger:    &fakeLedger{},
+		Matcher:   NewESMatcher(ssdtest.NewRepository(ssdtest.Drives...), 0),
+		Renderer:  MarkdownRenderer{},
+		Commenter: &fakeCommenter{comments: map[string]string{}},
+		Processed: store.NewMemoryStore(),
+		Misses:    misses,
+	}
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	got, err := misses.All()
+	if err != nil {
+		t.Fatalf("All() unexpected error = %v", err)
+	}
+	if len(got) != 1 || got[0].SubmissionID != "missed" || got[0].CleanedTitle != CleanTitle(submissions[1].Title) {
+		t.Fatalf("Run() queued %+v, want the missed submission", got)
+	}
+	if len(got[0].Candidates) != 1 || got[0].Candidates[0].DriveID != "1100" || !strings.HasPrefix(got[0].Candidates[0].Rejected, "P44 is missing from search query") {
+		t.Errorf("Run() queued candidates %+v, want the rejected P44 Pro", got[0].Candidates)
+	}
+}
+
+func TestRunNearTie(t *testing.T) {
+	submissions := []reddit.Submission{
+		{ID: "tie", Name: "t3_tie", Title: "[SSD] Solidigm or Corsair 2TB", LinkFlairText: "SSD"},
+	}
+	commenter := &fakeCommenter{comments: map[string]string{}}
+	p := &Pipeline{
+		Config:    config.Config{MatchNearTie: 0.05},
+		Rule:      configtest.Rule,
+		Source:    &fakeSource{submissions: submissions},
+		Ledger:    &fakeLedger{},
+		Matcher:   fakeMatcher{"[SSD] Solidigm or Corsair 2TB": {ssdtest.Drives[1], ssdtest.Drives[0]}},
+		Renderer:  MarkdownRenderer{},
+		Commenter: commenter,
+		Processed: store.NewMemoryStore(),
+	}
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	comment := commenter.comments["tie"]
+	if !strings.Contains(comment, "possibly one of") || !strings.Contains(comment, "Solidigm P44 Pro 2 TB") || !strings.Contains(comment, "Corsair MP600 Mini 1 TB") {
+		t.Errorf("Run() commented %q, want both drives as possibly one of", comment)
+	}
+	if record, _, _ := p.Processed.Get("tie"); record.DriveID != "1100" || !slices.Equal(record.DriveIDs, []string{"1100", "1461"}) {
+		t.Errorf("stored tie with drives %q and %v, want the best one and both", record.DriveID, record.DriveIDs)
+	}
+}
+
+func TestRunManyDrives(t *testing.T) {
+	submissions := []reddit.Submission{
+		{ID: "many", Name: "t3_many", Title: "[SSD] Corsair MP600 Mini 1TB $70 / Solidigm P44 Pro 2TB $130", LinkFlairText: "SSD"},
+	}
+	commenter := &fakeCommenter{comments: map[string]string{}}
+	p := &Pipeline{
+		Rule:   configtest.Rule,
+		Source: &fakeSource{submissions: submissions},
+		Ledger: &fakeLedger{},
+		Matcher: fakeMatcher{
+			"Corsair MP600 Mini 1TB $70": ssdtest.Drives[:1],
+			"Solidigm P44 Pro 2TB $130":  ssdtest.Drives[1:],
+		},
+		Renderer:  MarkdownRenderer{},
+		Commenter: commenter,
+		Processed: store.NewMemoryStore(),
+	}
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	comment := commenter.comments["many"]
+	if !strings.Contains(comment, "The Corsair MP600 Mini 1 TB") || !strings.Contains(comment, "The Solidigm P44 Pro 2 TB") {
+		t.Errorf("Run() commented %q, want a spec block for each drive", comment)
+	}
+	if record, _, _ := p.Processed.Get("many"); record.DriveID != "1461" || !slices.Equal(record.DriveIDs, []string{"1461", "1100"}) {
+		t.Errorf("stored drives %q and %v, want the first one and both", record.DriveID, record.DriveIDs)
+	}
+}
+
+func TestRunProcessedStore(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", OverrideOldBot: true}
+	p := newTestPipeline(cfg, configtest.Rule, rc, repo)
+
+	matched := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+	unknown := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Unknown brand 1TB", LinkFlairText: "SSD"})
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	posted := fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 1 {
+		t.Fatalf("Run() posted %d comments, want 1", len(posted))
+	}
+	record, ok, _ := p.Processed.Get(matched.ID)
+	if !ok || record.Outcome != store.OutcomeCommented || record.DriveID != "1100" || record.CommentName != posted[0].Name {
+		t.Errorf("stored %+v, want the comment %s on drive 1100", record, posted[0].Name)
+	}
+	if record, ok, _ := p.Processed.Get(unknown.ID); !ok || record.Outcome != store.OutcomeNoMatch {
+		t.Errorf("stored %+v, want no match", record)
+	}
+
+	// after a restart, neither a removed comment nor an unmatched title
+	// makes the bot look at the submissions again
+	if err := rc.DeleteComment(context.Background(), posted[0].Name); err != nil {
+		t.Fatalf("DeleteComment() unexpected error = %v", err)
+	}
+	searches := repo.Searches()
+	p.Source = reddit.NewSubmissionPoller(rc, configtest.Rule.Name, 25, 4)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if posted := fake.CommentsBy("_SSD_BOT_"); len(posted) != 0 {
+		t.Errorf("Run() after a restart posted %+v, want no comment", posted)
+	}
+	if repo.Searches() != searches {
+		t.Errorf("Run() after a restart searched %d more titles, want none", repo.Searches()-searches)
+	}
+}
+
+func TestRunSubredditRule(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{OverrideOldBot: true}
+	rule := config.SubredditRule{Name: "bapcsalescanada", TitleTags: []string{"SSD"}, Region: "ca"}
+	p := newTestPipeline(cfg, rule, rc, repo)
+
+	tagged := fake.AddSubmission(reddit.Submission{Subreddit: "bapcsalescanada", Title: "[SSD] Solidigm P44 Pro 2TB - $170", LinkFlairText: "Sale"})
+	fake.AddSubmission(reddit.Submission{Subreddit: "bapcsalescanada", Title: "Corsair MP600 Mini 1TB - $90", LinkFlairText: "SSD"})
+	fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Corsair MP600 Mini 1TB - $70", LinkFlairText: "SSD"})
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	posted := fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 1 || posted[0].LinkID != tagged.Name {
+		t.Fatalf("Run() posted %+v, want only a comment on %s", posted, tagged.Name)
+	}
+	if !strings.Contains(posted[0].Body, "https://ca.camelcamelcamel.com/") {
+		t.Errorf("Run() posted %q, want the price history of the ca region", posted[0].Body)
+	}
+}
+
+func TestRunCompetingBots(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", CompetingBots: []string{"SSDBot", "OtherSSDBot"}}
+	p := newTestPipeline(cfg, configtest.Rule, rc, repo)
+
+	buried := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Corsair MP600 Mini 1TB", LinkFlairText: "SSD"})
+	for i := 0; i < 150; i++ {
+		fake.AddComment(buried.ID, "someone", "nice price")
+	}
+	fake.AddComment(buried.ID, "otherssdbot", "specs")
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if posted := fake.CommentsBy("_SSD_BOT_"); len(posted) != 0 {
+		t.Errorf("Run() posted %+v, want no comment next to the competing bot after the first 100 comments", posted)
+	}
+	if fake.Requests("/api/morechildren") == 0 {
+		t.Errorf("Run() did not fetch the comments left out of the thread")
+	}
+
+	// comment anyway where the subreddit rule says so
+	rule := configtest.Rule
+	rule.CompetingBot = config.CompetingBotComment
+	p = newTestPipeline(cfg, rule, rc, repo)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if posted := fake.CommentsBy("_SSD_BOT_"); len(posted) != 1 || posted[0].LinkID != buried.Name {
+		t.Errorf("Run() posted %+v, want a comment on %s", posted, buried.Name)
+	}
+}
+
+func TestRunCompetingBotsError(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", CompetingBots: []string{"SSDBot"}}
+	p := newTestPipeline(cfg, configtest.Rule, rc, repo)
+
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+	fake.AddComment(submission.ID, "SSDBot", "specs")
+	fake.InjectStatus("/comments/"+submission.ID, http.StatusInternalServerError, 1)
+
+	// not knowing whether a competing bot commented must not lead to commenting
+	if err := p.Run(context.Background()); !errors.Is(err, reddit.ErrServer) {
+		t.Fatalf("Run() error = %v, want %v", err, reddit.ErrServer)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if posted := fake.CommentsBy("_SSD_BOT_"); len(posted) != 0 {
+		t.Errorf("Run() posted %+v, want no comment next to SSDBot", posted)
+	}
+	if got := fake.Requests("/comments/" + submission.ID); got != 2 {
+		t.Errorf("Run() read the comments %d times, want 2", got)
+	}
+}
+
+func TestRunSkipsLockedThread(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", OverrideOldBot: true}
+	p := newTestPipeline(cfg, configtest.Rule, rc, repo)
+
+	matched := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Corsair MP600 Mini 1TB", LinkFlairText: "SSD"})
+	fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+	fake.InjectAPIError("/api/comment", "THREAD_LOCKED", "that comment thread has been locked")
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	posted := fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 1 || posted[0].LinkID != matched.Name {
+		t.Errorf("Run() posted %+v, want only a comment on %s", posted, matched.Name)
+	}
+}
+
+func TestRunRetriesAfterRateLimit(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc := fake.Client(t)
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", OverrideOldBot: true}
+	p := newTestPipeline(cfg, configtest.Rule, rc, repo)
+
+	matched := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+	fake.InjectAPIError("/api/comment", "RATELIMIT", "you are doing that too much. try again in 9 minutes.")
+
+	err := p.Run(context.Background())
+	if retryAfter, _ := reddit.RetryAfter(err); !errors.Is(err, reddit.ErrRateLimited) || retryAfter != 9*time.Minute {
+		t.Fatalf("Run() error = %v, want a rate limit error with a 9m wait", err)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	posted := fake.CommentsBy("_SSD_BOT_")
+	if len(posted) != 1 || posted[0].LinkID != matched.Name {
+		t.Errorf("Run() posted %+v, want a comment on %s once the rate limit passed", posted, matched.Name)
+	}
+}
+
+func TestRunReadOnly(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc, err := reddit.NewRedditClient("id", "secret", "", "", "", 0, false, append(fake.Options(), reddit.WithClientCredentials())...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", OverrideOldBot: true}
+	p := newTestPipeline(cfg, configtest.Rule, rc, repo)
+
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if comments := fake.Comments(submission.ID); len(comments) != 0 {
+		t.Errorf("Run() with a read only client posted %v", comments)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	fake := reddittest.NewServer()
+	defer fake.Close()
+	rc, err := reddit.NewRedditClient("id", "secret", "", "", "", 0, false, append(fake.Options(), reddit.WithClientCredentials())...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	repo := ssdtest.NewRepository(ssdtest.Drives...)
+	cfg := config.Config{Subreddit: "buildapcsales", CompetingBots: []string{"SSDBot"}}
+	p := newTestPipeline(cfg, configtest.Rule, rc, repo)
+
+	matched := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Solidigm P44 Pro 2TB - $130", LinkFlairText: "SSD"})
+	unknown := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] Unknown brand 1TB", LinkFlairText: "SSD"})
+
+	var out bytes.Buffer
+	p.DryRun = NewDryRunLog(&out)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() unexpected error = %v", err)
+	}
+	if got := fake.Requests("/api/comment"); got != 0 {
+		t.Errorf("Run() in a dry run posted %d comments", got)
+	}
+
+	var records []DryRunRecord
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var record DryRunRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("decoding dry run record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Run() wrote %d dry run records, want 2", len(records))
+	}
+	for _, record := range records {
+		switch record.SubmissionID {
+		case matched.ID:
+			if record.Chosen == nil || record.Chosen.DriveID != "1100" || len(record.Candidates) != 1 || !strings.Contains(record.Markdown, "Solidigm P44 Pro 2 TB") {
+				t.Errorf("dry run record = %+v, want the P44 Pro chosen and rendered", record)
+			}
+		case unknown.ID:
+			if record.Chosen != nil || len(record.Candidates) != 0 || record.Markdown != "" {
+				t.Errorf("dry run record = %+v, want no drive", record)
+			}
+		default:
+			t.Errorf("dry run record for unexpected submission %s", record.SubmissionID)
+		}
+	}
+	if _, ok, _ := p.Processed.Get(unknown.ID); ok {
+		t.Errorf("Run() in a dry run stored the unmatched submission")
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		submission reddit.Submission
+		want       string
+	}{
+		{name: "eligible", submission: reddit.Submission{CreatedUTC: float64(now.Add(-time.Hour).Unix())}, want: ""},
+		{name: "locked", submission: reddit.Submission{Locked: true}, want: "submission is locked"},
+		{name: "archived", submission: reddit.Submission{Archived: true}, want: "submission is archived"},
+		{name: "removed", submission: reddit.Submission{RemovedByCategory: "moderator"}, want: "submission was removed (moderator)"},
+		{name: "deleted", submission: reddit.Submission{RemovedByCategory: "deleted"}, want: "submission was removed (deleted)"},
+		{name: "stickied", submission: reddit.Submission{Stickied: true}, want: "submission is stickied"},
+		{name: "nsfw", submission: reddit.Submission{Over18: true}, want: "submission is marked NSFW"},
+		{name: "too old", submission: reddit.Submission{CreatedUTC: float64(now.Add(-48 * time.Hour).Unix())}, want: "submission is older than 24h0m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipReason(tt.submission, now, 24*time.Hour); got != tt.want {
+				t.Errorf("skipReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
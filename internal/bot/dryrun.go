@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+)
+
+// DryRunRecord is what the bot would have done with a submission it searched
+// a drive for. Chosen and Markdown are empty when no drive matched.
+type DryRunRecord struct {
+	SubmissionID string    `json:"submissionId"`
+	Subreddit    string    `json:"subreddit"`
+	Title        string    `json:"title"`
+	Candidates   []string  `json:"candidates"`
+	Chosen       *ssd.SSD  `json:"chosen"`
+	Markdown     string    `json:"markdown"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// DryRunLog writes the comments of a dry run as JSON lines instead of
+// posting them.
+type DryRunLog struct {
+	enc *json.Encoder
+}
+
+// NewDryRunLog creates a dry run log writing to w.
+func NewDryRunLog(w io.Writer) *DryRunLog {
+	return &DryRunLog{enc: json.NewEncoder(w)}
+}
+
+// Write records a searched submission and the drives found for it. Every
+// candidate is listed with its confidence, the chosen drive is the best
+// accepted one.
+func (d *DryRunLog) Write(submission reddit.Submission, result MatchResult, markdown string) error {
+	record := DryRunRecord{
+		SubmissionID: submission.ID,
+		Subreddit:    submission.Subreddit,
+		Title:        submission.Title,
+		Candidates:   []string{},
+		Markdown:     markdown,
+		CreatedAt:    time.Now(),
+	}
+	for _, c := range result.Candidates {
+		record.Candidates = append(record.Candidates, fmt.Sprintf("%s %s %s (%s) %.2f", c.Drive.Manufacturer, c.Drive.Name, c.Drive.Capacity, c.Drive.DriveID, c.Confidence))
+	}
+	if drives := result.Drives(); len(drives) > 0 {
+		record.Chosen = &drives[0]
+	}
+	if err := d.enc.Encode(record); err != nil {
+		return fmt.Errorf("writing dry run record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+// RedditLedger is a CommentLedger reading the comments from reddit.
+type RedditLedger struct {
+	rc    *reddit.Client
+	limit int
+}
+
+// NewRedditLedger creates a ledger looking at the last limit comments of the
+// bot. The bot comments on every watched subreddit, so limit must reach far
+// enough back to still see its comments on the submissions of a poll.
+func NewRedditLedger(rc *reddit.Client, limit int) *RedditLedger {
+	return &RedditLedger{rc: rc, limit: limit}
+}
+
+// BotCommented returns the IDs of the submissions the bot recently commented
+// on. A client without a user context never comments, so nothing is returned.
+func (l *RedditLedger) BotCommented(ctx context.Context) (map[string]bool, error) {
+	commented := map[string]bool{}
+	if !l.rc.HasUserContext() {
+		return commented, nil
+	}
+	comments, err := l.rc.GetUserNewestComments(ctx, l.limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		commented[strings.TrimPrefix(comment.LinkID, "t3_")] = true
+	}
+	return commented, nil
+}
+
+// BotCommentedOn reads the comments of the submission, so it also finds the
+// comments older than the limit.
+func (l *RedditLedger) BotCommentedOn(ctx context.Context, submissionId string) (bool, error) {
+	if !l.rc.HasUserContext() {
+		return false, nil
+	}
+	author, err := l.rc.CommentedBy(ctx, submissionId, l.rc.Username())
+	return author != "", err
+}
+
+func (l *RedditLedger) CommentedBy(ctx context.Context, submissionId string, authors ...string) (string, error) {
+	return l.rc.CommentedBy(ctx, submissionId, authors...)
+}
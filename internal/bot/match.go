@@ -0,0 +1,209 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/rs/zerolog/log"
+)
+
+// ESMatcher is a Matcher searching the drive database and scoring how well
+// each hit agrees with the searched text.
+type ESMatcher struct {
+	repo      ssd.Repository
+	threshold float64
+}
+
+// NewESMatcher creates a matcher searching repo and accepting the drives with
+// at least the threshold confidence, DefaultThreshold when it is zero.
+func NewESMatcher(repo ssd.Repository, threshold float64) *ESMatcher {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	return &ESMatcher{repo: repo, threshold: threshold}
+}
+
+func (m *ESMatcher) Match(ctx context.Context, text string) (MatchResult, error) {
+	title := CleanTitle(text)
+	result, err := m.search(ctx, title)
+	if err != nil || len(result.Accepted()) > 0 {
+		return result, err
+	}
+	fallback, ok, err := m.nearestCapacity(ctx, title)
+	if err != nil || !ok {
+		return result, err
+	}
+	return fallback, nil
+}
+
+// nearestCapacity searches title again without its capacity, for when the
+// database lacks that capacity variant of the drive. The variant of the best
+// model closest to the capacity is ranked first. It returns false when the
+// title names no capacity, no variant is confident enough either or the best
+// model leaves out model words of the title, e.g. the 990 EVO for a
+// "990 EVO Plus".
+func (m *ESMatcher) nearestCapacity(ctx context.Context, title string) (MatchResult, bool, error) {
+	want, ok := ssd.ParseCapacityGB(title)
+	if !ok {
+		return MatchResult{}, false, nil
+	}
+	log.Info().Msgf("No drive of %s found, searching every capacity", ssd.FormatCapacity(want))
+	result, err := m.search(ctx, ssd.RemoveCapacities(title))
+	if err != nil {
+		return result, false, err
+	}
+	accepted := result.Accepted()
+	if len(accepted) == 0 || accepted[0].Signals.ModelText < 1 {
+		return result, false, nil
+	}
+	best := accepted[0].Drive
+	closest, distance := -1, 0.0
+	for i, c := range result.Candidates {
+		if c.Confidence < result.Threshold || c.Drive.Manufacturer != best.Manufacturer || c.Drive.Name != best.Name {
+			continue
+		}
+		got, ok := ssd.ParseCapacityGB(c.Drive.Capacity)
+		if !ok {
+			continue
+		}
+		if closest < 0 || math.Abs(got-want) < distance {
+			closest, distance = i, math.Abs(got-want)
+		}
+	}
+	if closest < 0 {
+		return result, false, nil
+	}
+	c := result.Candidates[closest]
+	result.Candidates = slices.Insert(slices.Delete(result.Candidates, closest, closest+1), 0, c)
+	result.RequestedCapacity = ssd.FormatCapacity(want)
+	log.Info().Msgf("Closest capacity to %s is %v", result.RequestedCapacity, c.Drive)
+	return result, true, nil
+}
+
+// search searches query and scores every hit, best match first.
+func (m *ESMatcher) search(ctx context.Context, query string) (MatchResult, error) {
+	result := MatchResult{Query: query, Threshold: m.threshold, Candidates: []Candidate{}}
+	hits, err := ssd.SearchScored(ctx, m.repo, query)
+	if err != nil {
+		return result, err
+	}
+	var maxScore float64
+	for _, hit := range hits {
+		maxScore = max(maxScore, hit.Score)
+	}
+	for _, hit := range hits {
+		c := scoreCandidate(query, hit, maxScore, m.threshold)
+		log.Debug().Msgf("%s %s scored %.2f: %+v", c.Drive.Manufacturer, c.Drive.Name, c.Confidence, c.Signals)
+		result.Candidates = append(result.Candidates, c)
+	}
+	rank(result.Candidates)
+	log.Info().Msgf("Final sorted filtered list %v", result.Drives())
+	return result, nil
+}
+
+// FindSSD looks up the drive best matching a submission title or any other
+// text naming a drive. It returns nil when no drive is confident enough.
+func FindSSD(ctx context.Context, m Matcher, text string) (*ssd.SSD, error) {
+	result, err := m.Match(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	drives := result.Drives()
+	if len(drives) == 0 {
+		return nil, nil
+	}
+	return &drives[0], nil
+}
+
+// rank sorts the candidates best match first: the most confident, then the
+// longest name, as it matched the most words of the title, then the newest
+// drive.
+func rank(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
+		}
+		iName := strings.ReplaceAll(candidates[i].Drive.Name, "(w/ Heatsink)", "")
+		jName := strings.ReplaceAll(candidates[j].Drive.Name, "(w/ Heatsink)", "")
+		if len(iName) != len(jName) {
+			return len(iName) > len(jName)
+		}
+		iID, jID := candidates[i].Drive.DriveID, candidates[j].Drive.DriveID
+		numI, errI := strconv.Atoi(iID)
+		numJ, errJ := strconv.Atoi(jID)
+		// If both are valid integers, compare numerically
+		if errI == nil && errJ == nil {
+			return numI > numJ
+		}
+		// If only one is valid, prefer the valid one
+		if errI == nil {
+			return true
+		}
+		if errJ == nil {
+			return false
+		}
+		// If neither is valid, fall back to string comparison
+		return iID > jID
+	})
+}
+
+// rejectReason returns the first word of the manufacturer and name of a
+// drive missing from the search query, or the first model word of the query
+// missing from the name, or an empty string when none is.
+func rejectReason(searchQuery string, ssd ssd.SSD) string {
+	query := squash(searchQuery)
+	if !strings.Contains(query, squash(ssd.Manufacturer)) {
+		return "manufacturer is missing from search query"
+	}
+	for _, word := range strings.Fields(strings.ReplaceAll(ssd.Name, "(w/ Heatsink)", "")) {
+		if !strings.Contains(query, squash(word)) {
+			return fmt.Sprintf("%s is missing from search query", word)
+		}
+	}
+	name := squash(strings.ReplaceAll(ssd.Name, "(w/ Heatsink)", ""))
+	for _, word := range modelText(searchQuery, ssd) {
+		if !strings.Contains(name, word) {
+			return fmt.Sprintf("%s is missing from the drive name", word)
+		}
+	}
+	return ""
+}
+
+// CleanTitle lowercases a submission title and removes the bracketed tags
+// and words that do not help finding the drive, then appends the full names
+// of known abbreviations.
+func CleanTitle(s string) string {
+	s = strings.ToLower(s)
+	s = regexp.MustCompile(`\[[^\]]+\]`).ReplaceAllString(s, "")
+
+	// Remove common strings that don't help with SSD identification
+	stringsToRemove := []string{"ssd", "m2", "m.2", "nvme", "pcie", "gen", "amazon"}
+	for _, toReplace := range stringsToRemove {
+		s = strings.ReplaceAll(s, toReplace, "")
+	}
+
+	// Build the result string with expansions
+	var builder strings.Builder
+	builder.WriteString(s)
+
+	// Add expansions for known abbreviations
+	stringsToReplace := map[string]string{
+		" wd":         " western digital",
+		"team group":  " teamgroup",
+		"spatium":     " msi spatium",
+		"sn850x":      " western digital sn850x",
+	}
+	for k, v := range stringsToReplace {
+		if strings.Contains(s, k) {
+			builder.WriteString(v)
+		}
+	}
+	return builder.String()
+}
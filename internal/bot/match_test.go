@@ -0,0 +1,376 @@
+package bot
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd/ssdtest"
+)
+
+func TestFindSSD(t *testing.T) {
+	matcher := NewESMatcher(ssdtest.NewRepository(
+		ssd.SSD{DriveID: "1", Manufacturer: "Western Digital", Name: "Black SN850X"},
+		ssd.SSD{DriveID: "2", Manufacturer: "Samsung", Name: "990 Pro"},
+		ssd.SSD{DriveID: "3", Manufacturer: "Samsung", Name: "990 EVO"},
+		ssd.SSD{DriveID: "4", Manufacturer: "Samsung", Name: "990 Pro"},
+		ssd.SSD{DriveID: "5", Manufacturer: "Samsung", Name: "Pro"},
+	), 0)
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{name: "abbreviated manufacturer", title: "[SSD] WD Black SN850X 2TB - $150", want: "1"},
+		{name: "longest name, then newest drive", title: "[SSD] Samsung 990 Pro 2TB NVMe - $170", want: "4"},
+		{name: "name words missing", title: "[SSD] Samsung 870 EVO 1TB - $60", want: ""},
+		{name: "manufacturer missing", title: "[SSD] 990 Pro 2TB", want: ""},
+		{name: "manufacturer implied by the model", title: "[SSD] Black SN850X 2TB", want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := FindSSD(context.Background(), matcher, tt.title)
+			if err != nil {
+				t.Fatalf("FindSSD() unexpected error = %v", err)
+			}
+			var got string
+			if found != nil {
+				got = found.DriveID
+			}
+			if got != tt.want {
+				t.Errorf("FindSSD() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	matcher := NewESMatcher(ssdtest.NewRepository(
+		ssd.SSD{DriveID: "2", Manufacturer: "Samsung", Name: "990 Pro", Capacity: "2 TB", FormFactor: "M.2 2280"},
+		ssd.SSD{DriveID: "3", Manufacturer: "Samsung", Name: "990 EVO", Capacity: "1 TB", FormFactor: "M.2 2280"},
+		ssd.SSD{DriveID: "6", Manufacturer: "Crucial", Name: "P3"},
+	), 0)
+	result, err := matcher.Match(context.Background(), "[SSD] Samsung 990 EVO 1TB")
+	if err != nil {
+		t.Fatalf("Match() unexpected error = %v", err)
+	}
+	if result.Query != CleanTitle("[SSD] Samsung 990 EVO 1TB") || result.Threshold != DefaultThreshold {
+		t.Errorf("Match() = %q at %v, want the cleaned title at the default threshold", result.Query, result.Threshold)
+	}
+	if len(result.Candidates) != 2 {
+		t.Fatalf("Match() = %+v, want the Samsung drives", result.Candidates)
+	}
+
+	best := result.Candidates[0]
+	want := Signals{Search: 0.5, Manufacturer: 1, Model: 1, ModelText: 1, Capacity: 1, FormFactor: 0.5, Variant: 1}
+	if best.Drive.DriveID != "3" || best.Signals != want || best.Rejected != "" {
+		t.Errorf("Match() best = %+v, want the 990 EVO with signals %+v", best, want)
+	}
+	rejected := result.Candidates[1]
+	if rejected.Drive.DriveID != "2" || rejected.Signals.Model != 0.5 || rejected.Signals.Capacity != 0 ||
+		rejected.Confidence >= DefaultThreshold || !strings.HasPrefix(rejected.Rejected, "Pro is missing from search query") {
+		t.Errorf("Match() second = %+v, want the 990 Pro rejected", rejected)
+	}
+	if drives := result.Drives(); len(drives) != 1 || drives[0].DriveID != "3" {
+		t.Errorf("Drives() = %v, want the 990 EVO only", drives)
+	}
+}
+
+// favouringRepository scores the favoured drive twice as high as the others,
+// like a search ranking a shorter name first.
+type favouringRepository struct {
+	*ssdtest.Repository
+	favoured string
+}
+
+func (r favouringRepository) SearchScored(ctx context.Context, s string) ([]ssd.ScoredSSD, error) {
+	found, err := r.Repository.Search(ctx, s)
+	var res []ssd.ScoredSSD
+	for _, drive := range found {
+		score := 1.0
+		if drive.DriveID == r.favoured {
+			score = 2
+		}
+		res = append(res, ssd.ScoredSSD{SSD: drive, Score: score})
+	}
+	return res, err
+}
+
+func TestMatchSubsetName(t *testing.T) {
+	repo := ssdtest.NewRepository(
+		ssd.SSD{DriveID: "10", Manufacturer: "Samsung", Name: "980 Pro", Capacity: "1 TB"},
+		ssd.SSD{DriveID: "11", Manufacturer: "Samsung", Name: "980", Capacity: "1 TB"},
+		ssd.SSD{DriveID: "12", Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "2 TB"},
+		ssd.SSD{DriveID: "13", Manufacturer: "Crucial", Name: "P3", Capacity: "2 TB"},
+	)
+	tests := []struct {
+		name     string
+		title    string
+		favoured string
+		want     string
+	}{
+		{name: "980 Pro", title: "[SSD] Samsung 980 Pro 1TB - $89", favoured: "11", want: "10"},
+		{name: "980", title: "[SSD] Samsung 980 1TB - $59", favoured: "10", want: "11"},
+		{name: "P3 Plus", title: "[SSD] Crucial P3 Plus 2TB NVMe Gen4 - $99", favoured: "13", want: "12"},
+		{name: "P3", title: "[SSD] Crucial P3 2TB - $89", favoured: "12", want: "13"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := NewESMatcher(favouringRepository{repo, tt.favoured}, 0)
+			result, err := matcher.Match(context.Background(), tt.title)
+			if err != nil {
+				t.Fatalf("Match() unexpected error = %v", err)
+			}
+			var got []string
+			for _, drive := range result.Drives() {
+				got = append(got, drive.DriveID)
+			}
+			if !slices.Equal(got, []string{tt.want}) {
+				t.Errorf("Match() = %v, want %s only", got, tt.want)
+			}
+			if ties := result.NearTies(0.05); ties != nil {
+				t.Errorf("NearTies() = %v, want nil", ties)
+			}
+		})
+	}
+}
+
+func TestSignals(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		drive ssd.SSD
+		want  func(Signals) bool
+	}{
+		{name: "heatsink wanted", query: "samsung 990 pro heatsink 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro (w/ Heatsink)"},
+			want: func(s Signals) bool { return s.Variant == 1 && s.Model == 1 }},
+		{name: "heatsink not wanted", query: "samsung 990 pro heatsink 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro"},
+			want: func(s Signals) bool { return s.Variant == 0 }},
+		{name: "heatsink not mentioned", query: "samsung 990 pro 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro (w/ Heatsink)"},
+			want: func(s Signals) bool { return s.Variant == 0.5 }},
+		{name: "capacity in GB", query: "crucial p3 500gb", drive: ssd.SSD{Manufacturer: "Crucial", Name: "P3", Capacity: "500 GB"},
+			want: func(s Signals) bool { return s.Capacity == 1 }},
+		{name: "capacity in TB", query: "crucial p3 1000gb", drive: ssd.SSD{Manufacturer: "Crucial", Name: "P3", Capacity: "1 TB"},
+			want: func(s Signals) bool { return s.Capacity == 1 }},
+		{name: "other form factor", query: "crucial p3 2230 1tb", drive: ssd.SSD{Manufacturer: "Crucial", Name: "P3", FormFactor: "M.2 2280"},
+			want: func(s Signals) bool { return s.FormFactor == 0 }},
+		{name: "manufacturer missing", query: "990 pro 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro"},
+			want: func(s Signals) bool { return s.Manufacturer == 0 && s.Confidence() == 0 }},
+		{name: "model text covered", query: "samsung 980 pro 1tb - $89", drive: ssd.SSD{Manufacturer: "Samsung", Name: "980 Pro"},
+			want: func(s Signals) bool { return s.ModelText == 1 }},
+		{name: "model text partly covered", query: "samsung 980 pro 1tb - $89", drive: ssd.SSD{Manufacturer: "Samsung", Name: "980"},
+			want: func(s Signals) bool { return s.ModelText == 0.5 }},
+		{name: "lone digits left out", query: "crucial p3 plus  4 - $99", drive: ssd.SSD{Manufacturer: "Crucial", Name: "P3 Plus"},
+			want: func(s Signals) bool { return s.ModelText == 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoreCandidate(tt.query, ssd.ScoredSSD{SSD: tt.drive}, 0, DefaultThreshold)
+			if !tt.want(got.Signals) {
+				t.Errorf("scoreCandidate() signals = %+v", got.Signals)
+			}
+		})
+	}
+}
+
+func TestNearTies(t *testing.T) {
+	candidate := func(id, name string, confidence float64) Candidate {
+		return Candidate{Drive: ssd.SSD{DriveID: id, Manufacturer: "Samsung", Name: name}, Confidence: confidence}
+	}
+	result := MatchResult{Threshold: 0.75, Candidates: []Candidate{
+		candidate("1", "990 Pro", 0.95),
+		candidate("2", "990 Pro (w/ Heatsink)", 0.93),
+		candidate("3", "990 EVO", 0.9),
+		candidate("4", "980 Pro", 0.8),
+		candidate("5", "970 EVO", 0.5),
+	}}
+	tests := []struct {
+		margin float64
+		want   []string
+	}{
+		{margin: 0, want: nil},
+		{margin: 0.03, want: nil},
+		{margin: 0.05, want: []string{"1", "3"}},
+		{margin: 1, want: []string{"1", "3", "4"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, drive := range result.NearTies(tt.margin) {
+			got = append(got, drive.DriveID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("NearTies(%v) = %v, want %v", tt.margin, got, tt.want)
+		}
+	}
+
+	// a name within the best one is not a near tie
+	result = MatchResult{Threshold: 0.75, Candidates: []Candidate{
+		{Drive: ssd.SSD{DriveID: "6", Manufacturer: "Crucial", Name: "P3 Plus"}, Confidence: 0.95},
+		{Drive: ssd.SSD{DriveID: "7", Manufacturer: "Crucial", Name: "P3"}, Confidence: 0.94},
+	}}
+	if got := result.NearTies(0.05); got != nil {
+		t.Errorf("NearTies() = %v, want nil", got)
+	}
+}
+
+func TestRejectReason(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		drive ssd.SSD
+		want  string
+	}{
+		{name: "passes", query: "samsung 990 pro 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro"}, want: ""},
+		{name: "heatsink ignored", query: "samsung 990 pro 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro (w/ Heatsink)"}, want: ""},
+		{name: "manufacturer spaces ignored", query: "westerndigital sn770", drive: ssd.SSD{Manufacturer: "Western Digital", Name: "SN770"}, want: ""},
+		{name: "manufacturer missing", query: "990 pro 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro"}, want: "manufacturer is missing from search query"},
+		{name: "name word missing", query: "samsung 990 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990 Pro"}, want: "Pro is missing from search query"},
+		{name: "model word missing", query: "samsung 990 pro 2tb", drive: ssd.SSD{Manufacturer: "Samsung", Name: "990"}, want: "pro is missing from the drive name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rejectReason(tt.query, tt.drive); got != tt.want {
+				t.Errorf("rejectReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMentions(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  []string
+	}{
+		{name: "single drive", title: "[SSD] Samsung 990 Pro 2TB - $170", want: []string{"Samsung 990 Pro 2TB - $170"}},
+		{name: "bundle", title: "[SSD] 2-pack Samsung 870 EVO", want: []string{"2-pack Samsung 870 EVO"}},
+		{name: "accessories stay with the drive", title: "[SSD] WD SN850X 2TB + heatsink + game code", want: []string{"WD SN850X 2TB + heatsink + game code"}},
+		{name: "bare capacity takes the model", title: "[SSD] Crucial P3 Plus 2TB $99 / 4TB $189", want: []string{"Crucial P3 Plus 2TB $99", "Crucial P3 Plus 4TB $189"}},
+		{name: "different drives", title: "[SSD] Samsung 990 Pro 2TB $170, WD SN850X 1TB w/ heatsink $90", want: []string{"Samsung 990 Pro 2TB $170", "WD SN850X 1TB with heatsink $90"}},
+		{name: "capped", title: "[SSD] Crucial P3 250GB / 500GB / 1TB / 2TB / 4TB", want: []string{"Crucial P3 250GB", "Crucial P3 500GB", "Crucial P3 1TB", "Crucial P3 2TB"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitMentions(tt.title); !slices.Equal(got, tt.want) {
+				t.Errorf("SplitMentions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTitle(t *testing.T) {
+	matcher := NewESMatcher(ssdtest.NewRepository(
+		ssd.SSD{DriveID: "7", Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "2 TB"},
+		ssd.SSD{DriveID: "8", Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "4 TB"},
+		ssd.SSD{DriveID: "2", Manufacturer: "Samsung", Name: "990 Pro", Capacity: "2 TB"},
+	), 0)
+	tests := []struct {
+		name     string
+		title    string
+		mentions int
+		want     []string
+	}{
+		{name: "single drive", title: "[SSD] Samsung 990 Pro 2TB", mentions: 1, want: []string{"2"}},
+		{name: "capacities of a model", title: "[SSD] Crucial P3 Plus 2TB $99 / 4TB $189", mentions: 2, want: []string{"7", "8"}},
+		{name: "same drive twice", title: "[SSD] Samsung 990 Pro 2TB $170 | Samsung 990 Pro 2TB $165", mentions: 2, want: []string{"2"}},
+		{name: "one mention unknown", title: "[SSD] Samsung 990 Pro 2TB / Kingston KC3000 2TB", mentions: 2, want: []string{"2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchTitle(context.Background(), matcher, tt.title)
+			if err != nil {
+				t.Fatalf("MatchTitle() unexpected error = %v", err)
+			}
+			var ids []string
+			for _, drive := range got.Drives {
+				ids = append(ids, drive.DriveID)
+			}
+			if len(got.Results) != tt.mentions || !slices.Equal(ids, tt.want) {
+				t.Errorf("MatchTitle() = %d results with drives %v, want %d with %v", len(got.Results), ids, tt.mentions, tt.want)
+			}
+		})
+	}
+}
+
+// capacityRepository searches like EsRepository, only returning the drives
+// of the capacity in the query, if any.
+type capacityRepository struct {
+	*ssdtest.Repository
+}
+
+func (r capacityRepository) Search(ctx context.Context, s string) ([]ssd.SSD, error) {
+	found, err := r.Repository.Search(ctx, s)
+	want, ok := ssd.ParseCapacityGB(s)
+	if err != nil || !ok {
+		return found, err
+	}
+	var res []ssd.SSD
+	for _, drive := range found {
+		if got, _ := ssd.ParseCapacityGB(drive.Capacity); got == want {
+			res = append(res, drive)
+		}
+	}
+	return res, nil
+}
+
+func TestMatchNearestCapacity(t *testing.T) {
+	matcher := NewESMatcher(capacityRepository{ssdtest.NewRepository(
+		ssd.SSD{DriveID: "6", Manufacturer: "Crucial", Name: "T500", Capacity: "2 TB"},
+		ssd.SSD{DriveID: "7", Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "500 GB"},
+		ssd.SSD{DriveID: "8", Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "1 TB"},
+		ssd.SSD{DriveID: "9", Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "4 TB"},
+		ssd.SSD{DriveID: "10", Manufacturer: "Samsung", Name: "990 EVO", Capacity: "2 TB"},
+	)}, 0)
+	tests := []struct {
+		name      string
+		title     string
+		want      string
+		requested string
+	}{
+		{name: "capacity found", title: "[SSD] Crucial P3 Plus 1TB", want: "8"},
+		{name: "closest smaller capacity", title: "[SSD] Crucial P3 Plus 2TB", want: "8", requested: "2 TB"},
+		{name: "closest larger capacity", title: "[SSD] Crucial P3 Plus 3.5TB", want: "9", requested: "3.5 TB"},
+		{name: "decimal capacity", title: "[SSD] Crucial P3 Plus 1.5TB", want: "8", requested: "1.5 TB"},
+		{name: "pack of drives", title: "[SSD] Crucial P3 Plus 2TBx2", want: "8", requested: "2 TB"},
+		{name: "count before the capacity", title: "[SSD] Crucial P3 Plus 2x2TB", want: "8", requested: "2 TB"},
+		{name: "no capacity", title: "[SSD] Crucial P3 Plus", want: "9"},
+		{name: "other model of the capacity", title: "[SSD] Crucial T500 2TB", want: "6"},
+		{name: "unknown model", title: "[SSD] Crucial T700 2TB", want: ""},
+		{name: "model the title names more of", title: "[SSD] Samsung 990 EVO Plus 4TB", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := matcher.Match(context.Background(), tt.title)
+			if err != nil {
+				t.Fatalf("Match() unexpected error = %v", err)
+			}
+			var got string
+			if drives := result.Drives(); len(drives) > 0 {
+				got = drives[0].DriveID
+			}
+			if got != tt.want || result.RequestedCapacity != tt.requested {
+				t.Errorf("Match() = %q instead of %q, want %q instead of %q", got, result.RequestedCapacity, tt.want, tt.requested)
+			}
+		})
+	}
+
+	lenient := NewESMatcher(matcher.repo, 0.65)
+	result, err := lenient.Match(context.Background(), "[SSD] Samsung 990 EVO Plus 4TB")
+	if err != nil {
+		t.Fatalf("Match() unexpected error = %v", err)
+	}
+	if drives := result.Drives(); len(drives) > 0 || result.RequestedCapacity != "" {
+		t.Errorf("Match() = %v instead of %q, want no closest capacity of another model", drives, result.RequestedCapacity)
+	}
+
+	match, err := MatchTitle(context.Background(), matcher, "[SSD] Crucial P3 Plus 2TB - $99")
+	if err != nil {
+		t.Fatalf("MatchTitle() unexpected error = %v", err)
+	}
+	comment := match.Render(MarkdownRenderer{}, "", 0)
+	if !strings.HasPrefix(comment, "*Specs shown for the Crucial P3 Plus 1 TB variant; the 2 TB may differ") {
+		t.Errorf("Render() = %q, want the capacity note first", comment)
+	}
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+)
+
+// maxMentions caps how many drive mentions of a title are searched.
+const maxMentions = 4
+
+var (
+	tagPattern          = regexp.MustCompile(`\[[^\]]+\]`)
+	withPattern         = regexp.MustCompile(`(?i)\bw/\s*`)
+	separatorPattern    = regexp.MustCompile(`(?i)\s*(?:/|\||\+|;|,\s|\s&\s|\sand\s|\sor\s)\s*`)
+	mentionCapacity     = regexp.MustCompile(`(?i)\d+(?:\.\d+)?\s*(?:tb|gb)\b`)
+	lettersPattern      = regexp.MustCompile(`[a-zA-Z]`)
+	mentionSpacePattern = regexp.MustCompile(`\s+`)
+)
+
+// SplitMentions splits a title into the drives it mentions, e.g.
+// "Crucial P3 Plus 2TB $99 / 4TB $189" into "Crucial P3 Plus 2TB $99" and
+// "Crucial P3 Plus 4TB $189". Every mention has its own capacity, the parts
+// without one, like "+ heatsink + game code", stay with the mention before
+// them and a bare capacity takes the model of the mention before it. A title
+// mentioning at most one capacity is a single mention.
+func SplitMentions(title string) []string {
+	title = tagPattern.ReplaceAllString(title, " ")
+	title = withPattern.ReplaceAllString(title, "with ")
+	if len(mentionCapacity.FindAllStringIndex(title, 2)) < 2 {
+		return []string{mentionSpacePattern.ReplaceAllString(strings.TrimSpace(title), " ")}
+	}
+
+	var mentions []string
+	for _, part := range separatorPattern.Split(title, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		loc := mentionCapacity.FindStringIndex(part)
+		if len(mentions) == 0 {
+			mentions = append(mentions, part)
+			continue
+		}
+		if loc == nil || !mentionCapacity.MatchString(mentions[len(mentions)-1]) {
+			mentions[len(mentions)-1] += " " + part
+			continue
+		}
+		if !lettersPattern.MatchString(part[:loc[0]]) {
+			// a bare capacity, e.g. "4TB $189"
+			part = modelOf(mentions[len(mentions)-1]) + " " + part
+		}
+		mentions = append(mentions, part)
+	}
+	for i := range mentions {
+		mentions[i] = mentionSpacePattern.ReplaceAllString(strings.TrimSpace(mentions[i]), " ")
+	}
+	if len(mentions) > maxMentions {
+		mentions = mentions[:maxMentions]
+	}
+	return mentions
+}
+
+// modelOf returns the text of a mention before its capacity.
+func modelOf(mention string) string {
+	if loc := mentionCapacity.FindStringIndex(mention); loc != nil {
+		return strings.TrimSpace(mention[:loc[0]])
+	}
+	return mention
+}
+
+// TitleMatch is the drives found for a title that may mention several.
+type TitleMatch struct {
+	// Mentions are the drive mentions the title was split into.
+	Mentions []string `json:"mentions"`
+	// Results are the match results of each mention.
+	Results []MatchResult `json:"results"`
+	// Drives are the best drive of each mention, without duplicates.
+	Drives []ssd.SSD `json:"drives"`
+}
+
+// MatchTitle matches each drive mention of a title on its own. A title with
+// a single mention is matched as a whole.
+func MatchTitle(ctx context.Context, m Matcher, title string) (TitleMatch, error) {
+	mentions := SplitMentions(title)
+	if len(mentions) <= 1 {
+		mentions = []string{title}
+	}
+	res := TitleMatch{Mentions: mentions, Drives: []ssd.SSD{}}
+	seen := map[string]bool{}
+	for _, mention := range mentions {
+		result, err := m.Match(ctx, mention)
+		if err != nil {
+			return res, err
+		}
+		res.Results = append(res.Results, result)
+		if drives := result.Drives(); len(drives) > 0 && !seen[drives[0].DriveID] {
+			seen[drives[0].DriveID] = true
+			res.Drives = append(res.Drives, drives[0])
+		}
+	}
+	return res, nil
+}
+
+// NearTies returns the near ties of a title mentioning a single drive, see
+// MatchResult.NearTies.
+func (t TitleMatch) NearTies(margin float64) []ssd.SSD {
+	if len(t.Results) != 1 {
+		return nil
+	}
+	return t.Results[0].NearTies(margin)
+}
+
+// Merged returns the results of every mention as one, the candidates of the
+// first mention first.
+func (t TitleMatch) Merged() MatchResult {
+	var merged MatchResult
+	var queries []string
+	for _, result := range t.Results {
+		queries = append(queries, result.Query)
+		merged.Threshold = result.Threshold
+		merged.Candidates = append(merged.Candidates, result.Candidates...)
+	}
+	merged.Query = strings.Join(queries, " | ")
+	return merged
+}
+
+// Render renders the comment about the drives found, with renderer and the
+// price history of region. It is empty when no drive was found.
+func (t TitleMatch) Render(renderer Renderer, region string, nearTieMargin float64) string {
+	if len(t.Drives) == 0 {
+		return ""
+	}
+	notes := t.capacityNotes()
+	if len(t.Drives) > 1 {
+		return notes + renderer.RenderMany(t.Drives, region)
+	}
+	if nearTies := t.NearTies(nearTieMargin); nearTies != nil {
+		return renderer.RenderNearTie(nearTies, region)
+	}
+	return notes + renderer.Render(t.Drives[0], region)
+}
+
+// capacityNotes notes every drive shown in another capacity than the one
+// its mention asked for.
+func (t TitleMatch) capacityNotes() string {
+	var b strings.Builder
+	for _, result := range t.Results {
+		if drives := result.Drives(); result.RequestedCapacity != "" && len(drives) > 0 {
+			b.WriteString(drives[0].CapacityNoteMarkdown(result.RequestedCapacity))
+		}
+	}
+	return b.String()
+}
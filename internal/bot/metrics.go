@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	submissionsSeen = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ssdbot_submissions_seen_total",
+		Help: "New submissions polled.",
+	}, []string{"subreddit"})
+	submissionsFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ssdbot_submissions_filtered_total",
+		Help: "Submissions not matching the flairs and title tags of the subreddit.",
+	}, []string{"subreddit"})
+	submissionsAlreadyCommented = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ssdbot_submissions_already_commented_total",
+		Help: "Submissions the bot already commented on.",
+	}, []string{"subreddit"})
+	submissionsMatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ssdbot_submissions_matched_total",
+		Help: "Submissions a drive was found for.",
+	}, []string{"subreddit"})
+	submissionsUnmatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ssdbot_submissions_unmatched_total",
+		Help: "Submissions no drive was found for.",
+	}, []string{"subreddit"})
+	commentsPosted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ssdbot_comments_posted_total",
+		Help: "Comments posted on submissions.",
+	}, []string{"subreddit"})
+	commentsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ssdbot_comments_failed_total",
+		Help: "Comments reddit rejected or that failed to post.",
+	}, []string{"subreddit"})
+)
@@ -0,0 +1,307 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+)
+
+// DefaultThreshold is the confidence a drive needs to be commented when no
+// threshold is configured.
+const DefaultThreshold = 0.75
+
+// weights of the signals besides the manufacturer, summing up to 1
+const (
+	modelWeight      = 0.6
+	searchWeight     = 0.1
+	capacityWeight   = 0.1
+	formFactorWeight = 0.05
+	variantWeight    = 0.15
+)
+
+// maxNearTies caps how many drives a "possibly one of" comment lists.
+const maxNearTies = 4
+
+// Signals are how well a drive agrees with the searched text, each from 0 to
+// 1. A signal the text says nothing about, e.g. the capacity of a title
+// without one, is 0.5.
+type Signals struct {
+	// Search is the search score relative to the best hit.
+	Search float64 `json:"search"`
+	// Manufacturer is 1 when the text names the manufacturer, 0 otherwise.
+	Manufacturer float64 `json:"manufacturer"`
+	// Model is the share of the words of the drive name in the text.
+	Model float64 `json:"model"`
+	// ModelText is the share of the model words of the text in the drive
+	// name, e.g. 0.5 for the 980 in "samsung 980 pro 1tb". It is 1 when the
+	// text has no model words around the name.
+	ModelText  float64 `json:"modelText"`
+	Capacity   float64 `json:"capacity"`
+	FormFactor float64 `json:"formFactor"`
+	// Variant tells whether the text and the drive agree on a heatsink.
+	Variant float64 `json:"variant"`
+}
+
+// Confidence weighs the signals into how sure the matcher is about a drive,
+// from 0 to 1. A drive whose manufacturer is not named gets 0 and the model
+// coverage is squared, so a missing name word costs more than its share. The
+// model text coverage scales the model weight, so the 980 does not pass for
+// a 980 Pro by a better search score.
+func (s Signals) Confidence() float64 {
+	return s.Manufacturer * (modelWeight*s.Model*s.Model*s.ModelText +
+		searchWeight*s.Search +
+		capacityWeight*s.Capacity +
+		formFactorWeight*s.FormFactor +
+		variantWeight*s.Variant)
+}
+
+// Candidate is a drive found for a text with how sure the matcher is about it.
+type Candidate struct {
+	Drive ssd.SSD `json:"drive"`
+	// Score is the score the search gave the drive.
+	Score      float64 `json:"score"`
+	Signals    Signals `json:"signals"`
+	Confidence float64 `json:"confidence"`
+	// Rejected tells why a drive below the threshold is not commented.
+	Rejected string `json:"rejected,omitempty"`
+}
+
+// MatchResult is every drive found for a text, best match first.
+type MatchResult struct {
+	// Query is the text as it was searched for.
+	Query      string      `json:"query"`
+	Threshold  float64     `json:"threshold"`
+	Candidates []Candidate `json:"candidates"`
+	// RequestedCapacity is the capacity the text names, e.g. "2 TB", when no
+	// drive has it and the candidates are of every capacity instead, the
+	// closest variant of the best model first.
+	RequestedCapacity string `json:"requestedCapacity,omitempty"`
+}
+
+// Accepted returns the candidates at or above the threshold, best first.
+func (r MatchResult) Accepted() []Candidate {
+	var res []Candidate
+	for _, c := range r.Candidates {
+		if c.Confidence >= r.Threshold {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Drives returns the drives of the accepted candidates, best first.
+func (r MatchResult) Drives() []ssd.SSD {
+	var res []ssd.SSD
+	for _, c := range r.Accepted() {
+		res = append(res, c.Drive)
+	}
+	return res
+}
+
+// NearTies returns the best drive and the best drive of every other model
+// within margin of its confidence, or nil when no other model is that close.
+// Capacities and heatsink variants of one model are not near ties, nor are
+// the drives of a closest capacity fallback, nor a model whose name words are
+// all in the name of the best one, e.g. the P3 when the P3 Plus is best.
+func (r MatchResult) NearTies(margin float64) []ssd.SSD {
+	accepted := r.Accepted()
+	if margin <= 0 || len(accepted) < 2 || r.RequestedCapacity != "" {
+		return nil
+	}
+	best := accepted[0].Drive
+	seen := map[string]bool{}
+	var res []ssd.SSD
+	for _, c := range accepted {
+		if accepted[0].Confidence-c.Confidence > margin || len(res) == maxNearTies {
+			break
+		}
+		model := strings.ToLower(c.Drive.Manufacturer + " " + strings.TrimSpace(strings.ReplaceAll(c.Drive.Name, "(w/ Heatsink)", "")))
+		if seen[model] || len(res) > 0 && nameSubset(c.Drive, best) {
+			continue
+		}
+		seen[model] = true
+		res = append(res, c.Drive)
+	}
+	if len(res) < 2 {
+		return nil
+	}
+	return res
+}
+
+// scoreCandidate computes the signals of a search hit for query, maxScore
+// being the best search score of the hits.
+func scoreCandidate(query string, hit ssd.ScoredSSD, maxScore, threshold float64) Candidate {
+	signals := Signals{
+		Search:     0.5,
+		Model:      modelCoverage(query, hit.Name),
+		ModelText:  modelTextCoverage(query, hit.SSD),
+		Capacity:   capacityAgreement(query, hit.Capacity),
+		FormFactor: formFactorAgreement(query, hit.FormFactor),
+		Variant:    variantAgreement(query, hit.Name),
+	}
+	if maxScore > 0 {
+		signals.Search = hit.Score / maxScore
+	}
+	if strings.Contains(squash(query), squash(hit.Manufacturer)) {
+		signals.Manufacturer = 1
+	}
+	c := Candidate{Drive: hit.SSD, Score: hit.Score, Signals: signals, Confidence: signals.Confidence()}
+	if c.Confidence < threshold {
+		c.Rejected = fmt.Sprintf("confidence %.2f is below %.2f", c.Confidence, threshold)
+		if reason := rejectReason(query, hit.SSD); reason != "" {
+			c.Rejected = reason + ", " + c.Rejected
+		}
+	}
+	return c
+}
+
+// squash lowercases s and removes its spaces, so "Western Digital" is found
+// in "westerndigital".
+func squash(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, " ", ""))
+}
+
+func modelCoverage(query, name string) float64 {
+	words := strings.Fields(strings.ReplaceAll(name, "(w/ Heatsink)", ""))
+	if len(words) == 0 {
+		return 0
+	}
+	found := 0
+	for _, word := range words {
+		if strings.Contains(squash(query), squash(word)) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
+
+// nameSubset reports whether every name word of drive is in the name of
+// other of the same manufacturer.
+func nameSubset(drive, other ssd.SSD) bool {
+	if !strings.EqualFold(drive.Manufacturer, other.Manufacturer) {
+		return false
+	}
+	words := strings.Fields(strings.ToLower(strings.ReplaceAll(other.Name, "(w/ Heatsink)", "")))
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(drive.Name, "(w/ Heatsink)", ""))) {
+		if !slices.Contains(words, word) {
+			return false
+		}
+	}
+	return true
+}
+
+var modelWordPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+-]*$`)
+
+// modelStopWords end the model text of a title, like the manufacturer, a
+// capacity, a form factor or a price do.
+var modelStopWords = map[string]bool{
+	"wd": true, "with": true, "w/": true, "and": true, "or": true, "for": true,
+	"heatsink": true, "heat": true, "sink": true, "hs": true,
+	"internal": true, "solid": true, "state": true, "drive": true,
+}
+
+// modelText returns the model words of query around the first one in the
+// name of drive, e.g. "980 pro" in "samsung 980 pro 1tb - $89" for the 980.
+// Lone digits, e.g. of "gen4", are left out.
+func modelText(query string, drive ssd.SSD) []string {
+	name := squash(strings.ReplaceAll(drive.Name, "(w/ Heatsink)", ""))
+	manufacturer := strings.Fields(strings.ToLower(drive.Manufacturer))
+	isModelWord := func(word string) bool {
+		_, isCapacity := ssd.ParseCapacityGB(word)
+		return modelWordPattern.MatchString(word) && !isCapacity &&
+			!slices.Contains(formFactors, word) && !modelStopWords[word] &&
+			!slices.Contains(manufacturer, word) && word != squash(drive.Manufacturer)
+	}
+	isNoise := func(word string) bool {
+		_, err := strconv.Atoi(word)
+		return err == nil && len(word) < 3
+	}
+
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		words = append(words, strings.Trim(word, ",;:!()"))
+	}
+	start := slices.IndexFunc(words, func(word string) bool {
+		return isModelWord(word) && !isNoise(word) && strings.Contains(name, word)
+	})
+	if start < 0 {
+		return nil
+	}
+	first, last := start, start
+	for first > 0 && isModelWord(words[first-1]) {
+		first--
+	}
+	for last+1 < len(words) && isModelWord(words[last+1]) {
+		last++
+	}
+	var res []string
+	for _, word := range words[first : last+1] {
+		if !isNoise(word) {
+			res = append(res, word)
+		}
+	}
+	return res
+}
+
+// modelTextCoverage is the share of the model text of query in the name of
+// drive.
+func modelTextCoverage(query string, drive ssd.SSD) float64 {
+	words := modelText(query, drive)
+	if len(words) == 0 {
+		return 1
+	}
+	name := squash(strings.ReplaceAll(drive.Name, "(w/ Heatsink)", ""))
+	found := 0
+	for _, word := range words {
+		if strings.Contains(name, word) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
+
+func capacityAgreement(query, capacity string) float64 {
+	want, ok := ssd.ParseCapacityGB(query)
+	got, gotOk := ssd.ParseCapacityGB(capacity)
+	if !ok || !gotOk {
+		return 0.5
+	}
+	if want == got {
+		return 1
+	}
+	return 0
+}
+
+var formFactors = []string{"2230", "2242", "2280", "22110"}
+
+func formFactorAgreement(query, formFactor string) float64 {
+	for _, ff := range formFactors {
+		if strings.Contains(query, ff) {
+			if strings.Contains(formFactor, ff) {
+				return 1
+			}
+			return 0
+		}
+	}
+	return 0.5
+}
+
+var heatsinkPattern = regexp.MustCompile(`(?i)heat\s*sink|\bw/\s*hs\b`)
+
+// variantAgreement prefers the heatsink variant of a drive when the text
+// mentions a heatsink and the plain drive otherwise.
+func variantAgreement(query, name string) float64 {
+	wantsHeatsink := heatsinkPattern.MatchString(query)
+	hasHeatsink := strings.Contains(name, "(w/ Heatsink)")
+	switch {
+	case wantsHeatsink == hasHeatsink:
+		return 1
+	case wantsHeatsink:
+		return 0
+	}
+	return 0.5
+}
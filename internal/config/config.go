@@ -1,12 +1,25 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 type Config struct {
 	// reddit config
 	ClientId     string `env:"CLIENT_ID,notEmpty"`
-	ClientSecret string `env:"CLIENT_SECRET,notEmpty"`
-	Username     string `env:"BOT_USERNAME,notEmpty"`
-	Password     string `env:"BOT_PASSWORD,notEmpty"`
-	Subreddit    string `env:"SUBREDDIT,notEmpty"`
+	ClientSecret string `env:"CLIENT_SECRET"`
+	Username     string `env:"BOT_USERNAME"`
+	Password     string `env:"BOT_PASSWORD"`
+	GrantType    string `env:"REDDIT_GRANT_TYPE" envDefault:"password"`
+	RefreshToken string `env:"REDDIT_REFRESH_TOKEN"`
+	Subreddit    string `env:"SUBREDDIT"`
+	RedditURL    string `env:"REDDIT_BASE_URL"`
+	RedditAuth   string `env:"REDDIT_AUTH_URL"`
+	TokenFile    string `env:"TOKEN_FILE"`
+
+	// JSON list of SubredditRule, watched instead of Subreddit when set
+	SubredditsFile string `env:"SUBREDDITS_FILE"`
 
 	// techpowerup config
 	TPUHost     string `env:"TPU_HOST,notEmpty"`
@@ -18,9 +31,70 @@ type Config struct {
 
 	// application config
 	OverrideOldBot bool `env:"OVERRIDE_OLD_BOT,notEmpty"`
+	// bots whose comment on a submission means this bot should not comment,
+	// unless OverrideOldBot is set or a subreddit rule says otherwise
+	CompetingBots  []string `env:"COMPETING_BOTS" envDefault:"SSDBot"`
+	PollMaxPages   int      `env:"POLL_MAX_PAGES" envDefault:"4"`
+	AnswerMentions bool     `env:"ANSWER_MENTIONS"`
+	AnswerMessages bool     `env:"ANSWER_MESSAGES"`
+	// replies to the bot's comments are stored as feedback in this file
+	FeedbackFile string `env:"FEEDBACK_FILE"`
+	// drives are only commented at this confidence, above 0 and at most 1
+	MatchThreshold float64 `env:"MATCH_THRESHOLD" envDefault:"0.75"`
+	// when drives of other models are within this confidence of the best
+	// one, the comment lists them all as "possibly one of", 0 to disable
+	MatchNearTie float64 `env:"MATCH_NEAR_TIE"`
+	// submissions no drive was found for are queued in this file for curation
+	MissesFile string `env:"MISSES_FILE"`
+	// processed submissions are stored in this bbolt database, in memory
+	// only when empty
+	StoreFile string `env:"STORE_FILE"`
+	// search and render as usual but write the comments as JSON lines to
+	// DryRunFile, or stdout when empty, instead of posting them
+	DryRun     bool   `env:"DRY_RUN"`
+	DryRunFile string `env:"DRY_RUN_FILE"`
+	// health checks and metrics are served on this address, not at all when empty
+	HTTPAddr string `env:"HTTP_ADDR" envDefault:":8080"`
+	// the admin API under /admin needs this bearer token, it is off when empty
+	AdminToken string `env:"ADMIN_TOKEN"`
+
+	// submissions older than this are not commented on
+	MaxSubmissionAge time.Duration `env:"MAX_SUBMISSION_AGE" envDefault:"24h"`
 
 	//debugging config
 	Token           string `env:"BOT_ACCESS_TOKEN"`
 	ExpireTimeMilli int64  `env:"BOT_TOKEN_EXPIRE_MILLI"`
 	IsDebug         bool   `env:"IS_DEBUG"`
 }
+
+// Validate checks the values env cannot, e.g. that MatchThreshold is a
+// confidence.
+func (cfg Config) Validate() error {
+	if cfg.MatchThreshold <= 0 || cfg.MatchThreshold > 1 {
+		return fmt.Errorf("MATCH_THRESHOLD must be above 0 and at most 1, got %v", cfg.MatchThreshold)
+	}
+	return nil
+}
+
+// Competing bot rules, what to do with a submission a competing bot already
+// commented on.
+const (
+	CompetingBotSkip    = "skip"
+	CompetingBotComment = "comment"
+)
+
+// SkipCompetingBots reports whether submissions of the subreddit of rule
+// that a competing bot already commented on are skipped. Subreddits whose
+// rule says nothing are skipped unless OverrideOldBot is set.
+func (cfg Config) SkipCompetingBots(rule SubredditRule) bool {
+	if len(cfg.CompetingBots) == 0 {
+		return false
+	}
+	switch rule.CompetingBot {
+	case CompetingBotSkip:
+		return true
+	case CompetingBotComment:
+		return false
+	}
+	return !cfg.OverrideOldBot
+}
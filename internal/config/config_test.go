@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestSkipCompetingBots(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		rule SubredditRule
+		want bool
+	}{
+		{name: "default", cfg: Config{CompetingBots: []string{"SSDBot"}}, rule: SubredditRule{Name: "buildapcsales"}, want: true},
+		{name: "override old bot", cfg: Config{CompetingBots: []string{"SSDBot"}, OverrideOldBot: true}, rule: SubredditRule{Name: "buildapcsales"}, want: false},
+		{name: "no competing bots", cfg: Config{}, rule: SubredditRule{Name: "buildapcsales", CompetingBot: CompetingBotSkip}, want: false},
+		{name: "comment rule", cfg: Config{CompetingBots: []string{"SSDBot"}}, rule: SubredditRule{Name: "buildapc", CompetingBot: CompetingBotComment}, want: false},
+		{name: "skip rule wins over override", cfg: Config{CompetingBots: []string{"SSDBot"}, OverrideOldBot: true}, rule: SubredditRule{Name: "buildapcsales", CompetingBot: CompetingBotSkip}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.SkipCompetingBots(tt.rule); got != tt.want {
+				t.Errorf("SkipCompetingBots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		wantErr   bool
+	}{
+		{threshold: 0.75},
+		{threshold: 1},
+		{threshold: 0, wantErr: true},
+		{threshold: -0.5, wantErr: true},
+		{threshold: 75, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := Config{MatchThreshold: tt.threshold}.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with MatchThreshold %v error = %v, wantErr %v", tt.threshold, err, tt.wantErr)
+		}
+	}
+}
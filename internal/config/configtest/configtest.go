@@ -0,0 +1,7 @@
+// Package configtest provides the subreddit rule the pipeline tests watch.
+package configtest
+
+import "github.com/aattwwss/ssd-bot-go/internal/config"
+
+// Rule watches r/buildapcsales for submissions flaired SSD.
+var Rule = config.SubredditRule{Name: "buildapcsales", Flairs: []string{"SSD"}}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+)
+
+// DefaultPollInterval is how often a subreddit without a poll interval is polled.
+const DefaultPollInterval = 15 * time.Minute
+
+// defaultFlairs are the flairs of subreddits with neither flairs nor title tags.
+var defaultFlairs = []string{"SSD"}
+
+// SubredditRule is how the bot treats the submissions of one subreddit.
+type SubredditRule struct {
+	Name string `json:"name"`
+	// Flairs and TitleTags select the submissions to comment on. A submission
+	// is selected when its link flair contains one of Flairs, e.g. "SSD"
+	// matches "SSD - M.2", or a bracketed tag of its title contains one of
+	// TitleTags, e.g. "SSD" matches "[SSD - NVME] ...". Case is ignored.
+	Flairs    []string `json:"flairs"`
+	TitleTags []string `json:"titleTags"`
+	// Region is the camelcamelcamel site linked for price history, e.g. "ca".
+	Region       string   `json:"region"`
+	PollInterval Duration `json:"pollInterval"`
+	Disabled     bool     `json:"disabled"`
+	// CompetingBot is what to do with a submission a competing bot already
+	// commented on, CompetingBotSkip or CompetingBotComment. When empty,
+	// OVERRIDE_OLD_BOT decides.
+	CompetingBot string `json:"competingBot"`
+}
+
+// Duration is a time.Duration read from JSON as a string like "15m".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+var titleTagPattern = regexp.MustCompile(`\[([^\]]+)\]`)
+
+// Matches reports whether the bot should look up the drive of a submission.
+func (r SubredditRule) Matches(submission reddit.Submission) bool {
+	flair := strings.ToUpper(submission.LinkFlairText)
+	for _, f := range r.Flairs {
+		if flair != "" && strings.Contains(flair, strings.ToUpper(f)) {
+			return true
+		}
+	}
+	for _, match := range titleTagPattern.FindAllStringSubmatch(submission.Title, -1) {
+		tag := strings.ToUpper(match[1])
+		for _, t := range r.TitleTags {
+			if strings.Contains(tag, strings.ToUpper(t)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withDefaults fills in the fields left empty.
+func (r SubredditRule) withDefaults() SubredditRule {
+	if len(r.Flairs) == 0 && len(r.TitleTags) == 0 {
+		r.Flairs = defaultFlairs
+	}
+	if r.Region == "" {
+		r.Region = "us"
+	}
+	if r.PollInterval.Duration <= 0 {
+		r.PollInterval.Duration = DefaultPollInterval
+	}
+	return r
+}
+
+// SubredditRules returns the rules of the subreddits to watch, read from
+// SubredditsFile when set, otherwise a default rule for Subreddit.
+func (cfg Config) SubredditRules() ([]SubredditRule, error) {
+	if cfg.SubredditsFile == "" {
+		if cfg.Subreddit == "" {
+			return nil, errors.New("either SUBREDDIT or SUBREDDITS_FILE must be set")
+		}
+		return []SubredditRule{SubredditRule{Name: cfg.Subreddit}.withDefaults()}, nil
+	}
+
+	data, err := os.ReadFile(cfg.SubredditsFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading subreddits file: %w", err)
+	}
+	var rules []SubredditRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("decoding subreddits file: %w", err)
+	}
+
+	seen := map[string]bool{}
+	enabled := 0
+	for i, rule := range rules {
+		rule.Name = strings.TrimPrefix(strings.TrimSpace(rule.Name), "r/")
+		rule.CompetingBot = strings.ToLower(rule.CompetingBot)
+		switch {
+		case rule.Name == "":
+			return nil, fmt.Errorf("subreddit %d has no name", i)
+		case seen[strings.ToLower(rule.Name)]:
+			return nil, fmt.Errorf("subreddit %s is listed more than once", rule.Name)
+		case rule.Region != "" && !ssd.IsPriceRegion(rule.Region):
+			return nil, fmt.Errorf("subreddit %s has unknown region %q", rule.Name, rule.Region)
+		case rule.CompetingBot != "" && rule.CompetingBot != CompetingBotSkip && rule.CompetingBot != CompetingBotComment:
+			return nil, fmt.Errorf("subreddit %s has unknown competingBot %q, want %s or %s", rule.Name, rule.CompetingBot, CompetingBotSkip, CompetingBotComment)
+		}
+		seen[strings.ToLower(rule.Name)] = true
+		if !rule.Disabled {
+			enabled++
+		}
+		rules[i] = rule.withDefaults()
+	}
+	if enabled == 0 {
+		return nil, errors.New("subreddits file has no enabled subreddit")
+	}
+	return rules, nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+func TestSubredditRuleMatches(t *testing.T) {
+	rule := SubredditRule{Flairs: []string{"SSD"}, TitleTags: []string{"SSD", "NVME"}}
+	tests := []struct {
+		name       string
+		submission reddit.Submission
+		want       bool
+	}{
+		{name: "flair", submission: reddit.Submission{Title: "Samsung 990 Pro 2TB", LinkFlairText: "SSD - M.2"}, want: true},
+		{name: "flair case", submission: reddit.Submission{Title: "Samsung 990 Pro 2TB", LinkFlairText: "ssd"}, want: true},
+		{name: "title tag", submission: reddit.Submission{Title: "[SSD - NVME] Samsung 990 Pro 2TB"}, want: true},
+		{name: "second title tag", submission: reddit.Submission{Title: "[CA] [NVMe] Samsung 990 Pro 2TB", LinkFlairText: "Storage"}, want: true},
+		{name: "untagged title", submission: reddit.Submission{Title: "Samsung SSD 990 Pro 2TB", LinkFlairText: "GPU"}, want: false},
+		{name: "other flair", submission: reddit.Submission{Title: "[GPU] RTX 4090", LinkFlairText: "GPU"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Matches(tt.submission); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubredditRules(t *testing.T) {
+	rules, err := Config{Subreddit: "buildapcsales"}.SubredditRules()
+	want := []SubredditRule{{Name: "buildapcsales", Flairs: []string{"SSD"}, Region: "us", PollInterval: Duration{DefaultPollInterval}}}
+	if err != nil || !reflect.DeepEqual(rules, want) {
+		t.Errorf("SubredditRules() without a file = %+v, %v, want %+v", rules, err, want)
+	}
+	if _, err := (Config{}).SubredditRules(); err == nil {
+		t.Errorf("SubredditRules() without a subreddit error = nil, want an error")
+	}
+
+	file := filepath.Join(t.TempDir(), "subreddits.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[
+		{"name": "buildapcsales", "flairs": ["SSD"]},
+		{"name": "r/bapcsalescanada", "titleTags": ["SSD"], "region": "ca", "pollInterval": "30m", "competingBot": "Comment"},
+		{"name": "hardwareswap", "flairs": ["Selling"], "disabled": true}
+	]`)
+	rules, err = Config{Subreddit: "ignored", SubredditsFile: file}.SubredditRules()
+	if err != nil {
+		t.Fatalf("SubredditRules() unexpected error = %v", err)
+	}
+	want = []SubredditRule{
+		{Name: "buildapcsales", Flairs: []string{"SSD"}, Region: "us", PollInterval: Duration{DefaultPollInterval}},
+		{Name: "bapcsalescanada", TitleTags: []string{"SSD"}, Region: "ca", PollInterval: Duration{30 * time.Minute}, CompetingBot: CompetingBotComment},
+		{Name: "hardwareswap", Flairs: []string{"Selling"}, Region: "us", PollInterval: Duration{DefaultPollInterval}, Disabled: true},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("SubredditRules() = %+v, want %+v", rules, want)
+	}
+
+	rules, err = Config{SubredditsFile: "../../subreddits.example.json"}.SubredditRules()
+	if err != nil || len(rules) != 4 {
+		t.Errorf("SubredditRules() of the example file = %+v, %v, want 4 rules", rules, err)
+	}
+
+	invalid := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "no name", content: `[{"flairs": ["SSD"]}]`, wantErr: "has no name"},
+		{name: "duplicate", content: `[{"name": "buildapcsales"}, {"name": "BuildAPCSales"}]`, wantErr: "more than once"},
+		{name: "region", content: `[{"name": "buildapcsales", "region": "mars"}]`, wantErr: "unknown region"},
+		{name: "interval", content: `[{"name": "buildapcsales", "pollInterval": 15}]`, wantErr: "duration"},
+		{name: "competing bot", content: `[{"name": "buildapcsales", "competingBot": "ignore"}]`, wantErr: "unknown competingBot"},
+		{name: "all disabled", content: `[{"name": "buildapcsales", "disabled": true}]`, wantErr: "no enabled subreddit"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			write(tt.content)
+			_, err := Config{SubredditsFile: file}.SubredditRules()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SubredditRules() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Package curation queues the submissions no drive was found for, with the
+// search candidates and why each was rejected, so missing aliases can be
+// added to the matcher and missing drives synced to the database.
+package curation
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/internal/jsonl"
+)
+
+// UnknownManufacturer groups the misses no candidate manufacturer appears in.
+const UnknownManufacturer = "unknown"
+
+// Candidate is a drive the search returned for a missed submission.
+type Candidate struct {
+	DriveID      string `json:"driveId"`
+	Manufacturer string `json:"manufacturer"`
+	Name         string `json:"name"`
+	Capacity     string `json:"capacity"`
+	// Score is the search score and Confidence how sure the matcher was.
+	Score      float64 `json:"score"`
+	Confidence float64 `json:"confidence"`
+	// Rejected tells why the drive was not commented, e.g.
+	// "manufacturer is missing from search query".
+	Rejected string `json:"rejected,omitempty"`
+}
+
+// Miss is a submission no drive was found for.
+type Miss struct {
+	SubmissionID string `json:"submissionId"`
+	Subreddit    string `json:"subreddit"`
+	Title        string `json:"title"`
+	// CleanedTitle is the title as it was searched for.
+	CleanedTitle string      `json:"cleanedTitle"`
+	Candidates   []Candidate `json:"candidates"`
+	CreatedAt    time.Time   `json:"createdAt"`
+}
+
+// LikelyManufacturer returns the manufacturer of the best scored candidate
+// that the cleaned title names, or UnknownManufacturer when there is none.
+func (m Miss) LikelyManufacturer() string {
+	title := strings.ReplaceAll(strings.ToLower(m.CleanedTitle), " ", "")
+	best := -1
+	for i, c := range m.Candidates {
+		manufacturer := strings.ReplaceAll(strings.ToLower(c.Manufacturer), " ", "")
+		if manufacturer == "" || !strings.Contains(title, manufacturer) {
+			continue
+		}
+		if best < 0 || c.Score > m.Candidates[best].Score {
+			best = i
+		}
+	}
+	if best < 0 {
+		return UnknownManufacturer
+	}
+	return m.Candidates[best].Manufacturer
+}
+
+// Store persists misses.
+type Store interface {
+	Add(miss Miss) error
+	All() ([]Miss, error)
+}
+
+// FileStore is a Store appending misses as JSON lines to a file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a curation queue backed by the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) Add(miss Miss) error {
+	return jsonl.Append(s.path, miss)
+}
+
+// All returns every stored miss, oldest first. A missing file has no misses.
+func (s *FileStore) All() ([]Miss, error) {
+	return jsonl.All[Miss](s.path)
+}
+
+// Group is the misses of one likely manufacturer.
+type Group struct {
+	Manufacturer string
+	Misses       []Miss
+}
+
+// GroupByManufacturer groups misses by likely manufacturer, the largest group
+// first and the unknown manufacturer last. When a submission was missed more
+// than once only its latest miss is kept.
+func GroupByManufacturer(misses []Miss) []Group {
+	latest := map[string]Miss{}
+	var order []string
+	for _, miss := range misses {
+		if _, ok := latest[miss.SubmissionID]; !ok {
+			order = append(order, miss.SubmissionID)
+		}
+		latest[miss.SubmissionID] = miss
+	}
+
+	byManufacturer := map[string]*Group{}
+	var groups []*Group
+	for _, id := range order {
+		miss := latest[id]
+		manufacturer := miss.LikelyManufacturer()
+		key := strings.ToLower(manufacturer)
+		g, ok := byManufacturer[key]
+		if !ok {
+			g = &Group{Manufacturer: manufacturer}
+			byManufacturer[key] = g
+			groups = append(groups, g)
+		}
+		g.Misses = append(g.Misses, miss)
+	}
+
+	res := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		res = append(res, *g)
+	}
+	slices.SortStableFunc(res, func(a, b Group) int {
+		if (a.Manufacturer == UnknownManufacturer) != (b.Manufacturer == UnknownManufacturer) {
+			if a.Manufacturer == UnknownManufacturer {
+				return 1
+			}
+			return -1
+		}
+		if n := cmp.Compare(len(b.Misses), len(a.Misses)); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Manufacturer, b.Manufacturer)
+	})
+	return res
+}
+
+// WriteReport writes the groups as plain text, listing at most candidates
+// search candidates per miss.
+func WriteReport(w io.Writer, groups []Group, candidates int) error {
+	var b strings.Builder
+	for i, g := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s (%d)\n", g.Manufacturer, len(g.Misses))
+		for _, miss := range g.Misses {
+			fmt.Fprintf(&b, "  %s r/%s %s\n", miss.SubmissionID, miss.Subreddit, miss.Title)
+			fmt.Fprintf(&b, "    searched: %s\n", strings.TrimSpace(miss.CleanedTitle))
+			for j, c := range miss.Candidates {
+				if j == candidates {
+					fmt.Fprintf(&b, "    ... %d more\n", len(miss.Candidates)-j)
+					break
+				}
+				fmt.Fprintf(&b, "    %.2f (score %.2f) #%s %s %s %s: %s\n", c.Confidence, c.Score, c.DriveID, c.Manufacturer, c.Name, c.Capacity, c.Rejected)
+			}
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package curation
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "misses.jsonl"))
+
+	misses, err := store.All()
+	if err != nil || misses != nil {
+		t.Fatalf("All() on a missing file = %v, %v, want nil, nil", misses, err)
+	}
+
+	want := []Miss{
+		{SubmissionID: "abc", Title: "[SSD] Samsung 870 EVO 1TB", CleanedTitle: " samsung 870 evo 1tb", Candidates: []Candidate{
+			{DriveID: "3", Manufacturer: "Samsung", Name: "990 EVO", Score: 7.5, Rejected: "990 is missing from search query"},
+		}},
+		{SubmissionID: "def", Title: "[SSD] Unknown brand 1TB", CleanedTitle: " unknown brand 1tb", Candidates: []Candidate{}},
+	}
+	for _, miss := range want {
+		if err := store.Add(miss); err != nil {
+			t.Fatalf("Add() unexpected error = %v", err)
+		}
+	}
+	misses, err = store.All()
+	if err != nil {
+		t.Fatalf("All() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(misses, want) {
+		t.Errorf("All() = %+v, want %+v", misses, want)
+	}
+}
+
+func TestLikelyManufacturer(t *testing.T) {
+	tests := []struct {
+		name       string
+		title      string
+		candidates []Candidate
+		want       string
+	}{
+		{name: "no candidates", title: "unknown brand 1tb", want: UnknownManufacturer},
+		{name: "manufacturer not in title", title: "990 pro 2tb", candidates: []Candidate{{Manufacturer: "Samsung", Score: 9}}, want: UnknownManufacturer},
+		{name: "best scored named manufacturer", title: "samsung crucial 1tb", candidates: []Candidate{
+			{Manufacturer: "Western Digital", Score: 12},
+			{Manufacturer: "Crucial", Score: 3},
+			{Manufacturer: "Samsung", Score: 5},
+		}, want: "Samsung"},
+		{name: "spaces ignored", title: "westerndigital blue", candidates: []Candidate{{Manufacturer: "Western Digital", Score: 1}}, want: "Western Digital"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			miss := Miss{CleanedTitle: tt.title, Candidates: tt.candidates}
+			if got := miss.LikelyManufacturer(); got != tt.want {
+				t.Errorf("LikelyManufacturer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupByManufacturer(t *testing.T) {
+	samsung := []Candidate{{DriveID: "3", Manufacturer: "Samsung", Name: "990 EVO", Capacity: "1 TB", Score: 7.5, Confidence: 0.42, Rejected: "990 is missing from search query"}}
+	crucial := []Candidate{{DriveID: "9", Manufacturer: "Crucial", Name: "P3 Plus", Score: 4, Rejected: "Plus is missing from search query"}}
+	misses := []Miss{
+		{SubmissionID: "a", Subreddit: "buildapcsales", Title: "[SSD] Crucial P3 1TB", CleanedTitle: " crucial p3 1tb", Candidates: crucial},
+		{SubmissionID: "b", Subreddit: "buildapcsales", Title: "[SSD] Samsung 870 EVO 1TB", CleanedTitle: " samsung 870 evo 1tb", Candidates: samsung},
+		{SubmissionID: "c", Subreddit: "buildapcsales", Title: "[SSD] Mystery 1TB", CleanedTitle: " mystery 1tb"},
+		{SubmissionID: "d", Subreddit: "buildapcsales", Title: "[SSD] Samsung 870 QVO 4TB", CleanedTitle: " samsung 870 qvo 4tb", Candidates: samsung},
+		// missed again, only the latest miss counts
+		{SubmissionID: "a", Subreddit: "buildapcsales", Title: "[SSD] Samsung 870 EVO 2TB", CleanedTitle: " samsung 870 evo 2tb", Candidates: samsung},
+	}
+
+	groups := GroupByManufacturer(misses)
+	var got []string
+	for _, g := range groups {
+		var ids []string
+		for _, miss := range g.Misses {
+			ids = append(ids, miss.SubmissionID)
+		}
+		got = append(got, g.Manufacturer+":"+strings.Join(ids, ","))
+	}
+	want := []string{"Samsung:a,b,d", "unknown:c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupByManufacturer() = %v, want %v", got, want)
+	}
+
+	var b strings.Builder
+	if err := WriteReport(&b, groups, 1); err != nil {
+		t.Fatalf("WriteReport() unexpected error = %v", err)
+	}
+	for _, line := range []string{
+		"Samsung (3)\n",
+		"  b r/buildapcsales [SSD] Samsung 870 EVO 1TB\n",
+		"    searched: samsung 870 evo 1tb\n",
+		"    0.42 (score 7.50) #3 Samsung 990 EVO 1 TB: 990 is missing from search query\n",
+		"\nunknown (1)\n",
+	} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("WriteReport() = %q, want it to contain %q", b.String(), line)
+		}
+	}
+}
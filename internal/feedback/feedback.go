@@ -0,0 +1,136 @@
+// Package feedback stores corrections users reply to the bot's comments, so
+// wrongly matched drives can be added to the matcher regression set.
+package feedback
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/internal/jsonl"
+)
+
+// Verdict is what a user said about the drive the bot picked.
+type Verdict string
+
+const (
+	// VerdictWrong is a "!wrong <model>" reply, the model being optional.
+	VerdictWrong Verdict = "wrong"
+	// VerdictCorrect is a "!correct" reply.
+	VerdictCorrect Verdict = "correct"
+	// VerdictComment is a reply from the submission's author without either
+	// command, it has to be labelled by hand.
+	VerdictComment Verdict = "comment"
+)
+
+// Record is a piece of feedback on a comment of the bot.
+type Record struct {
+	SubmissionID string `json:"submissionId"`
+	Title        string `json:"title"`
+	// DriveID and Model are the drive the bot picked that the reply is
+	// about, Model being the manufacturer and name, e.g. "Solidigm P44 Pro".
+	// Both are empty when the comment showed several drives and the reply
+	// named none of them.
+	DriveID string `json:"driveId"`
+	Model   string `json:"model"`
+	// DriveIDs are every drive the comment showed, best match first.
+	DriveIDs []string `json:"driveIds,omitempty"`
+	Verdict  Verdict  `json:"verdict"`
+	// SuggestedModel is the model given with a "!wrong <model>" reply.
+	SuggestedModel string    `json:"suggestedModel,omitempty"`
+	Author         string    `json:"author"`
+	IsSubmitter    bool      `json:"isSubmitter"`
+	CommentName    string    `json:"commentName"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// LabelledModel returns the model the submission is about according to the
+// feedback, or an empty string when the record is not labelled.
+func (r Record) LabelledModel() string {
+	switch r.Verdict {
+	case VerdictCorrect:
+		return r.Model
+	case VerdictWrong:
+		return r.SuggestedModel
+	}
+	return ""
+}
+
+var (
+	wrongPattern   = regexp.MustCompile(`(?im)(?:^|\s)!wrong\b[ \t:,-]*(.*)$`)
+	correctPattern = regexp.MustCompile(`(?i)(?:^|\s)!correct\b`)
+)
+
+// Parse finds a "!wrong <model>" or "!correct" command in a reply and
+// returns the verdict and suggested model.
+func Parse(body string) (Verdict, string, bool) {
+	if match := wrongPattern.FindStringSubmatch(body); match != nil {
+		return VerdictWrong, strings.TrimSpace(match[1]), true
+	}
+	if correctPattern.MatchString(body) {
+		return VerdictCorrect, "", true
+	}
+	return "", "", false
+}
+
+// Store persists feedback records.
+type Store interface {
+	Add(record Record) error
+	All() ([]Record, error)
+}
+
+// FileStore is a Store appending records as JSON lines to a file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a feedback store backed by the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) Add(record Record) error {
+	return jsonl.Append(s.path, record)
+}
+
+// All returns every stored record, oldest first. A missing file has no records.
+func (s *FileStore) All() ([]Record, error) {
+	return jsonl.All[Record](s.path)
+}
+
+// WriteTSV writes the labelled records in the layout of test/test_data.csv:
+// submission id, title and model separated by tabs, with CRLF line endings.
+// When a submission got several labelled records the latest one wins.
+// It returns the number of rows written.
+func WriteTSV(w io.Writer, records []Record) (int, error) {
+	latest := map[string]Record{}
+	var order []string
+	for _, record := range records {
+		if record.LabelledModel() == "" {
+			continue
+		}
+		if _, ok := latest[record.SubmissionID]; !ok {
+			order = append(order, record.SubmissionID)
+		}
+		latest[record.SubmissionID] = record
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = '\t'
+	writer.UseCRLF = true
+	for _, id := range order {
+		record := latest[id]
+		if err := writer.Write([]string{record.SubmissionID, record.Title, record.LabelledModel(), ""}); err != nil {
+			return 0, fmt.Errorf("writing record: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return 0, fmt.Errorf("writing record: %w", err)
+	}
+	return len(order), nil
+}
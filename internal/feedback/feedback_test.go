@@ -0,0 +1,80 @@
+package feedback
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantVerdict Verdict
+		wantModel   string
+		wantOk      bool
+	}{
+		{name: "wrong with model", body: "!wrong WD SN770", wantVerdict: VerdictWrong, wantModel: "WD SN770", wantOk: true},
+		{name: "wrong in sentence", body: "nope\n\n!WRONG: Crucial P3 Plus\nthanks", wantVerdict: VerdictWrong, wantModel: "Crucial P3 Plus", wantOk: true},
+		{name: "wrong without model", body: "!wrong", wantVerdict: VerdictWrong, wantModel: "", wantOk: true},
+		{name: "correct", body: "good bot !correct", wantVerdict: VerdictCorrect, wantModel: "", wantOk: true},
+		{name: "no command", body: "wrong drive, this is the SN770", wantOk: false},
+		{name: "not a command", body: "this is!wrong", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, model, ok := Parse(tt.body)
+			if verdict != tt.wantVerdict || model != tt.wantModel || ok != tt.wantOk {
+				t.Errorf("Parse() = %q, %q, %v, want %q, %q, %v", verdict, model, ok, tt.wantVerdict, tt.wantModel, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "feedback.jsonl"))
+
+	records, err := store.All()
+	if err != nil || records != nil {
+		t.Fatalf("All() on a missing file = %v, %v, want nil, nil", records, err)
+	}
+
+	want := []Record{
+		{SubmissionID: "abc", Title: "[SSD] WD SN770 1TB", DriveID: "1", Model: "WD SN850X", Verdict: VerdictWrong, SuggestedModel: "WD SN770"},
+		{SubmissionID: "def", Title: "[SSD] Crucial P3", DriveID: "2", Model: "Crucial P3", Verdict: VerdictCorrect},
+	}
+	for _, record := range want {
+		if err := store.Add(record); err != nil {
+			t.Fatalf("Add() unexpected error = %v", err)
+		}
+	}
+	records, err = store.All()
+	if err != nil {
+		t.Fatalf("All() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("All() = %+v, want %+v", records, want)
+	}
+}
+
+func TestWriteTSV(t *testing.T) {
+	records := []Record{
+		{SubmissionID: "12qer8b", Title: "[SSD - M.2] Solidigm P44 Pro 2TB - $130", Model: "Solidigm P44 Pro", Verdict: VerdictCorrect},
+		{SubmissionID: "127fs77", Title: `[SSD] Intel Optane 905P 960GB, U.2/M.2 2.5" - $339`, Model: "Intel Optane 900P", Verdict: VerdictWrong, SuggestedModel: "Intel Optane 905P"},
+		{SubmissionID: "nolabel", Title: "[SSD] something", Model: "Crucial P3", Verdict: VerdictWrong},
+		{SubmissionID: "comment", Title: "[SSD] something else", Model: "Crucial P3", Verdict: VerdictComment},
+		{SubmissionID: "12qer8b", Title: "[SSD - M.2] Solidigm P44 Pro 2TB - $130", Model: "Solidigm P44 Pro", Verdict: VerdictWrong, SuggestedModel: "Solidigm P41 Plus"},
+	}
+
+	var b strings.Builder
+	n, err := WriteTSV(&b, records)
+	if err != nil {
+		t.Fatalf("WriteTSV() unexpected error = %v", err)
+	}
+	want := "12qer8b\t[SSD - M.2] Solidigm P44 Pro 2TB - $130\tSolidigm P41 Plus\t\r\n" +
+		"127fs77\t\"[SSD] Intel Optane 905P 960GB, U.2/M.2 2.5\"\" - $339\"\tIntel Optane 905P\t\r\n"
+	if n != 2 || b.String() != want {
+		t.Errorf("WriteTSV() = %d, %q, want 2, %q", n, b.String(), want)
+	}
+}
@@ -0,0 +1,46 @@
+package jsonl
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type line struct {
+	ID   string `json:"id"`
+	Seen int    `json:"seen"`
+}
+
+func TestAppendAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lines.jsonl")
+	got, err := All[line](path)
+	if err != nil || got != nil {
+		t.Fatalf("All() of a missing file = %v, %v, want nothing", got, err)
+	}
+
+	want := []line{{ID: "a", Seen: 1}, {ID: "b", Seen: 2}}
+	for _, l := range want {
+		if err := Append(path, l); err != nil {
+			t.Fatalf("Append() unexpected error = %v", err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() unexpected error = %v", err)
+	}
+	f.WriteString("\n")
+	f.Close()
+	got, err = All[line](path)
+	if err != nil || !slices.Equal(got, want) {
+		t.Errorf("All() = %v, %v, want %v", got, err, want)
+	}
+
+	if err := os.WriteFile(path, []byte("{\"id\":\"a\"}\nnot json\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error = %v", err)
+	}
+	if _, err := All[line](path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("All() error = %v, want the broken line", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Package redditclient maps the config to the options of the reddit client
+// shared by the commands, keeping pkg/reddit out of the config package.
+package redditclient
+
+import (
+	"fmt"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+// Options returns the reddit client options selected by the config. An
+// unknown grant type is an error.
+func Options(cfg config.Config) ([]reddit.Option, error) {
+	var opts []reddit.Option
+	switch reddit.GrantType(cfg.GrantType) {
+	case reddit.GrantPassword:
+	case reddit.GrantRefreshToken:
+		opts = append(opts, reddit.WithRefreshToken(cfg.RefreshToken))
+	case reddit.GrantClientCredentials:
+		opts = append(opts, reddit.WithClientCredentials())
+	default:
+		return nil, fmt.Errorf("unknown REDDIT_GRANT_TYPE %q, want %s, %s or %s",
+			cfg.GrantType, reddit.GrantPassword, reddit.GrantRefreshToken, reddit.GrantClientCredentials)
+	}
+	if cfg.RedditURL != "" {
+		opts = append(opts, reddit.WithBaseURL(cfg.RedditURL))
+	}
+	if cfg.RedditAuth != "" {
+		opts = append(opts, reddit.WithAuthURL(cfg.RedditAuth))
+	}
+	if cfg.TokenFile != "" {
+		opts = append(opts, reddit.WithTokenStore(reddit.NewFileTokenStore(cfg.TokenFile)))
+	}
+	return opts, nil
+}
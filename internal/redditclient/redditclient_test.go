@@ -0,0 +1,33 @@
+package redditclient
+
+import (
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/internal/config"
+)
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		wantOpts int
+		wantErr  bool
+	}{
+		{name: "password", cfg: config.Config{GrantType: "password"}, wantOpts: 0},
+		{name: "refresh token", cfg: config.Config{GrantType: "refresh_token", RefreshToken: "token"}, wantOpts: 1},
+		{name: "client credentials", cfg: config.Config{GrantType: "client_credentials"}, wantOpts: 1},
+		{name: "urls and token file", cfg: config.Config{GrantType: "password", RedditURL: "http://localhost:8081", RedditAuth: "http://localhost:8081/api/v1/access_token", TokenFile: "token.json"}, wantOpts: 3},
+		{name: "unknown grant type", cfg: config.Config{GrantType: "refresh"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := Options(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Options() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(opts) != tt.wantOpts {
+				t.Errorf("Options() = %d options, want %d", len(opts), tt.wantOpts)
+			}
+		})
+	}
+}
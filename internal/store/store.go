@@ -0,0 +1,227 @@
+// Package store remembers which submissions the bot already processed, so
+// they are neither searched nor commented on again, even across restarts.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Outcome is what the bot did with a submission.
+type Outcome string
+
+const (
+	// OutcomeCommented means the bot commented the specs of a drive.
+	OutcomeCommented Outcome = "commented"
+	// OutcomeNoMatch means no drive in the database matched the title.
+	OutcomeNoMatch Outcome = "noMatch"
+	// OutcomeSkipped means the bot was not to comment, e.g. because the
+	// submission was locked or a competing bot already commented.
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeIgnored means an admin told the bot to leave the submission alone.
+	OutcomeIgnored Outcome = "ignored"
+	// OutcomeQueued means the submission is to be processed again, because an
+	// admin reprocessed it or its comment was held back while posting was
+	// paused.
+	OutcomeQueued Outcome = "queued"
+)
+
+// Record is a processed submission.
+type Record struct {
+	SubmissionID string  `json:"submissionId"`
+	Subreddit    string  `json:"subreddit"`
+	Title        string  `json:"title"`
+	Outcome      Outcome `json:"outcome"`
+	// Reason tells why a submission was skipped.
+	Reason string `json:"reason,omitempty"`
+	// DriveID and CommentName are the drive the bot commented, the first
+	// one when the title mentioned several, and the fullname of its comment,
+	// e.g. "t1_abc".
+	DriveID string `json:"driveId,omitempty"`
+	// DriveIDs are all the drives the bot commented, best match first.
+	DriveIDs    []string  `json:"driveIds,omitempty"`
+	CommentName string    `json:"commentName,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Store persists processed submissions by submission ID.
+type Store interface {
+	// Get returns the record of a submission and whether there is one.
+	Get(submissionID string) (Record, bool, error)
+	// Put saves a record, keeping the CreatedAt of an earlier record of the
+	// same submission.
+	Put(record Record) error
+	// Delete forgets a submission, so it is processed again.
+	Delete(submissionID string) error
+	// Recent returns the limit most recently updated records, newest first.
+	Recent(limit int) ([]Record, error)
+}
+
+var submissionsBucket = []byte("submissions")
+
+// BoltStore is a Store in a bbolt database file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the database at path, creating it if needed. It fails
+// when another process holds the database.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(submissionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating store bucket: %w", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Get(submissionID string) (Record, bool, error) {
+	var record Record
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(submissionsBucket).Get([]byte(submissionID))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &record)
+	})
+	if err != nil {
+		return Record{}, false, fmt.Errorf("reading submission %s: %w", submissionID, err)
+	}
+	return record, ok, nil
+}
+
+func (s *BoltStore) Put(record Record) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(submissionsBucket)
+		var previous Record
+		if data := bucket.Get([]byte(record.SubmissionID)); data != nil {
+			if err := json.Unmarshal(data, &previous); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(stamp(record, previous, time.Now()))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(record.SubmissionID), data)
+	})
+	if err != nil {
+		return fmt.Errorf("writing submission %s: %w", record.SubmissionID, err)
+	}
+	return nil
+}
+
+func (s *BoltStore) Delete(submissionID string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(submissionsBucket).Delete([]byte(submissionID))
+	})
+	if err != nil {
+		return fmt.Errorf("deleting submission %s: %w", submissionID, err)
+	}
+	return nil
+}
+
+// Recent reads and sorts every record, which is cheap for the few thousand
+// submissions a year the bot looks at.
+func (s *BoltStore) Recent(limit int) ([]Record, error) {
+	var records []Record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(submissionsBucket).ForEach(func(k, v []byte) error {
+			var record Record
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("decoding submission %s: %w", k, err)
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading submissions: %w", err)
+	}
+	return newest(records, limit), nil
+}
+
+// Close releases the database file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// MemoryStore is a Store forgetting everything on restart, used when no
+// database file is configured.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func (s *MemoryStore) Get(submissionID string) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[submissionID]
+	return record, ok, nil
+}
+
+func (s *MemoryStore) Put(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.SubmissionID] = stamp(record, s.records[record.SubmissionID], time.Now())
+	return nil
+}
+
+func (s *MemoryStore) Delete(submissionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, submissionID)
+	return nil
+}
+
+func (s *MemoryStore) Recent(limit int) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records := make([]Record, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	return newest(records, limit), nil
+}
+
+// newest sorts records by UpdatedAt, newest first, and keeps the first limit.
+func newest(records []Record, limit int) []Record {
+	slices.SortFunc(records, func(a, b Record) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	if limit >= 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records
+}
+
+// stamp sets the timestamps of a record replacing previous, which is the zero
+// Record when the submission was not stored yet.
+func stamp(record, previous Record, now time.Time) Record {
+	record.CreatedAt = previous.CreatedAt
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	record.UpdatedAt = now
+	return record
+}
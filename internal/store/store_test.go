@@ -0,0 +1,73 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "submissions.db")
+	bolt, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStore() unexpected error = %v", err)
+	}
+	defer func() { bolt.Close() }()
+
+	stores := []struct {
+		name  string
+		store Store
+	}{
+		{name: "bolt", store: bolt},
+		{name: "memory", store: NewMemoryStore()},
+	}
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok, err := tt.store.Get("abc"); ok || err != nil {
+				t.Fatalf("Get() of a new store = %v, %v, want nothing", ok, err)
+			}
+			if err := tt.store.Put(Record{SubmissionID: "abc", Subreddit: "buildapcsales", Outcome: OutcomeNoMatch}); err != nil {
+				t.Fatalf("Put() unexpected error = %v", err)
+			}
+			first, ok, err := tt.store.Get("abc")
+			if !ok || err != nil || first.Outcome != OutcomeNoMatch || first.CreatedAt.IsZero() {
+				t.Fatalf("Get() = %+v, %v, %v, want the stored record", first, ok, err)
+			}
+
+			if err := tt.store.Put(Record{SubmissionID: "abc", Outcome: OutcomeCommented, DriveID: "1100", CommentName: "t1_c1"}); err != nil {
+				t.Fatalf("Put() unexpected error = %v", err)
+			}
+			got, _, _ := tt.store.Get("abc")
+			if got.Outcome != OutcomeCommented || got.DriveID != "1100" || got.CommentName != "t1_c1" {
+				t.Errorf("Get() = %+v, want the replaced record", got)
+			}
+			if !got.CreatedAt.Equal(first.CreatedAt) || got.UpdatedAt.Before(first.UpdatedAt) {
+				t.Errorf("Get() = %+v, want CreatedAt kept at %v", got, first.CreatedAt)
+			}
+
+			if err := tt.store.Put(Record{SubmissionID: "def", Outcome: OutcomeIgnored}); err != nil {
+				t.Fatalf("Put() unexpected error = %v", err)
+			}
+			if recent, err := tt.store.Recent(1); err != nil || len(recent) != 1 || recent[0].SubmissionID != "def" {
+				t.Errorf("Recent(1) = %+v, %v, want the last updated record", recent, err)
+			}
+			if err := tt.store.Delete("def"); err != nil {
+				t.Fatalf("Delete() unexpected error = %v", err)
+			}
+			if _, ok, err := tt.store.Get("def"); ok || err != nil {
+				t.Errorf("Get() after Delete() = %v, %v, want nothing", ok, err)
+			}
+		})
+	}
+
+	// the records survive a restart
+	if err := bolt.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+	bolt, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStore() unexpected error = %v", err)
+	}
+	if got, ok, err := bolt.Get("abc"); !ok || err != nil || got.DriveID != "1100" {
+		t.Errorf("Get() after reopening = %+v, %v, %v, want the stored record", got, ok, err)
+	}
+}
@@ -1,7 +1,9 @@
 package reddit
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -21,49 +23,158 @@ const (
 	initialRetryDelay      = 500 * time.Millisecond
 )
 
+const (
+	defaultBaseURL = "https://oauth.reddit.com"
+	defaultAuthURL = "https://www.reddit.com/api/v1/access_token"
+)
+
 type tokenRes struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
 	ExpiresIn   int    `json:"expires_in"`
 	Scope       string `json:"scope"`
+	Error       string `json:"error"`
 }
 
+// GrantType is the OAuth grant used to obtain access tokens.
+type GrantType string
+
+const (
+	// GrantPassword logs in as the bot account with a script app.
+	GrantPassword GrantType = "password"
+	// GrantRefreshToken uses a refresh token issued to an installed or web app.
+	GrantRefreshToken GrantType = "refresh_token"
+	// GrantClientCredentials is application only and can only read public data.
+	GrantClientCredentials GrantType = "client_credentials"
+)
+
 // Client is a Reddit API client that handles authentication and requests.
 type Client struct {
 	httpClient     *http.Client
+	baseURL        string
+	authURL        string
 	clientId       string
 	clientSecret   string
 	username       string
 	password       string
+	grantType      GrantType
+	refreshToken   string
 	overrideOldBot bool
 
 	accessToken          string
 	tokenExpireTimeMilli int64
+	tokenStore           TokenStore
 	mu                   sync.RWMutex
+
+	rateLimiter *rateLimiter
 }
 
-// NewRedditClient creates a new Reddit client with the provided credentials.
-func NewRedditClient(clientId, clientSecret, username, password, accessToken string, expireTimeMilli int64, overrideOldBot bool) (*Client, error) {
-	if clientId == "" || clientSecret == "" || username == "" || password == "" {
-		return nil, fmt.Errorf("clientId, clientSecret, username, and password are required")
+// Option configures optional behaviour of a Client.
+type Option func(*Client)
+
+// WithBaseURL overrides the OAuth API base URL (default https://oauth.reddit.com).
+func WithBaseURL(baseURL string) Option {
+	return func(rc *Client) {
+		rc.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithAuthURL overrides the access token endpoint (default https://www.reddit.com/api/v1/access_token).
+func WithAuthURL(authURL string) Option {
+	return func(rc *Client) {
+		rc.authURL = authURL
+	}
+}
+
+// WithHTTPClient sets the http.Client used for every request.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(rc *Client) {
+		rc.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the RoundTripper of the default http.Client.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(rc *Client) {
+		rc.httpClient = &http.Client{
+			Timeout:   httpTimeout,
+			Transport: transport,
+		}
+	}
+}
+
+// WithRefreshToken uses the refresh_token grant instead of the password grant.
+// The client secret may be empty for installed apps.
+func WithRefreshToken(refreshToken string) Option {
+	return func(rc *Client) {
+		rc.grantType = GrantRefreshToken
+		rc.refreshToken = refreshToken
 	}
+}
 
+// WithClientCredentials uses the application only client_credentials grant.
+// Such a client can read public listings but methods acting as a user, like
+// SubmitComment, return ErrUserContextRequired.
+func WithClientCredentials() Option {
+	return func(rc *Client) {
+		rc.grantType = GrantClientCredentials
+	}
+}
+
+// WithTokenStore loads the access token from store when the client is created
+// and saves every refreshed token to it.
+func WithTokenStore(store TokenStore) Option {
+	return func(rc *Client) {
+		rc.tokenStore = store
+	}
+}
+
+// NewRedditClient creates a new Reddit client with the provided credentials.
+// The password grant is used unless an option selects another grant type, in
+// which case username and password may be empty.
+// A token loaded from the token store is used when it outlives accessToken
+// and was issued for the same grant type and user.
+func NewRedditClient(clientId, clientSecret, username, password, accessToken string, expireTimeMilli int64, overrideOldBot bool, opts ...Option) (*Client, error) {
 	httpClient := &http.Client{
 		Timeout: httpTimeout,
 	}
 
 	rc := Client{
 		httpClient:           httpClient,
+		baseURL:              defaultBaseURL,
+		authURL:              defaultAuthURL,
 		clientId:             clientId,
 		clientSecret:         clientSecret,
 		username:             username,
 		password:             password,
+		grantType:            GrantPassword,
 		overrideOldBot:       overrideOldBot,
 		accessToken:          accessToken,
 		tokenExpireTimeMilli: expireTimeMilli,
 	}
+	for _, opt := range opts {
+		opt(&rc)
+	}
+	if err := rc.validate(); err != nil {
+		return nil, err
+	}
+	rc.rateLimiter = newRateLimiter()
+	rc.httpClient = withRateLimit(rc.httpClient, rc.rateLimiter)
+
+	if rc.tokenStore != nil {
+		token, err := rc.tokenStore.Load()
+		if err != nil {
+			log.Error().Err(err).Msg("Error loading stored token, logging in again")
+		} else if token != nil && !rc.issuedFor(*token) {
+			log.Info().Msgf("Ignoring stored %s token of %q, logging in with %s as %q", token.GrantType, token.Username, rc.grantType, rc.Username())
+		} else if token != nil && token.Expiry.UnixMilli() > rc.tokenExpireTimeMilli {
+			log.Info().Msgf("Reusing stored token expiring at %v", token.Expiry)
+			rc.accessToken = token.AccessToken
+			rc.tokenExpireTimeMilli = token.Expiry.UnixMilli()
+		}
+	}
 
-	err := rc.RefreshToken()
+	err := rc.RefreshToken(context.Background())
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +182,86 @@ func NewRedditClient(clientId, clientSecret, username, password, accessToken str
 	return &rc, nil
 }
 
-func (rc *Client) RefreshToken() error {
+func (rc *Client) validate() error {
+	switch rc.grantType {
+	case GrantPassword:
+		if rc.clientId == "" || rc.clientSecret == "" || rc.username == "" || rc.password == "" {
+			return fmt.Errorf("clientId, clientSecret, username, and password are required")
+		}
+	case GrantRefreshToken:
+		// the token does not tell whose it is, but finding the bot's own
+		// comments needs the username
+		if rc.clientId == "" || rc.refreshToken == "" || rc.username == "" {
+			return fmt.Errorf("clientId, refresh token, and username are required")
+		}
+	case GrantClientCredentials:
+		if rc.clientId == "" || rc.clientSecret == "" {
+			return fmt.Errorf("clientId and clientSecret are required")
+		}
+	default:
+		return fmt.Errorf("unsupported grant type: %s", rc.grantType)
+	}
+	return nil
+}
+
+// Username returns the user the client acts as, empty for application only
+// clients.
+func (rc *Client) Username() string {
+	if !rc.HasUserContext() {
+		return ""
+	}
+	return rc.username
+}
+
+// issuedFor reports whether a stored token was issued for the grant type and
+// user of the client, so switching either never reuses the other's token.
+func (rc *Client) issuedFor(token Token) bool {
+	return token.GrantType == rc.grantType && strings.EqualFold(token.Username, rc.Username())
+}
+
+// GrantType returns the OAuth grant the client uses.
+func (rc *Client) GrantType() GrantType {
+	return rc.grantType
+}
+
+// HasUserContext reports whether the client acts as a Reddit user. Posting
+// comments and reading the user's own comments need a user context, which
+// the client_credentials grant does not provide.
+func (rc *Client) HasUserContext() bool {
+	return rc.grantType != GrantClientCredentials
+}
+
+// requireUserContext returns ErrUserContextRequired for application only clients.
+func (rc *Client) requireUserContext() error {
+	if !rc.HasUserContext() {
+		return ErrUserContextRequired
+	}
+	if rc.username == "" {
+		return fmt.Errorf("%w: username is not set", ErrUserContextRequired)
+	}
+	return nil
+}
+
+// withRateLimit returns a copy of httpClient whose transport records the
+// budget in limiter.
+func withRateLimit(httpClient *http.Client, limiter *rateLimiter) *http.Client {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	limited := *httpClient
+	limited.Transport = &rateLimitTransport{base: base, limiter: limiter}
+	return &limited
+}
+
+// RateLimit returns the request budget last reported by Reddit.
+func (rc *Client) RateLimit() RateLimit {
+	return rc.rateLimiter.get()
+}
+
+// RefreshToken fetches a new access token when the current one expires within
+// the refresh threshold.
+func (rc *Client) RefreshToken(ctx context.Context) error {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
 
@@ -82,17 +272,22 @@ func (rc *Client) RefreshToken() error {
 	}
 	// Set the form data
 	data := url.Values{}
-	data.Set("grant_type", "password")
-	data.Set("username", rc.username)
-	data.Set("password", rc.password)
+	data.Set("grant_type", string(rc.grantType))
+	switch rc.grantType {
+	case GrantPassword:
+		data.Set("username", rc.username)
+		data.Set("password", rc.password)
+	case GrantRefreshToken:
+		data.Set("refresh_token", rc.refreshToken)
+	}
 
 	// Create a new POST request
-	req, err := http.NewRequest("POST", "https://www.reddit.com/api/v1/access_token", strings.NewReader(data.Encode()))
-	req.Header.Add("User-Agent", userAgent)
+	req, err := http.NewRequestWithContext(ctx, "POST", rc.authURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		log.Error().Msgf("Error creating request: %v", err)
 		return err
 	}
+	req.Header.Add("User-Agent", userAgent)
 
 	// Set the content type header
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
@@ -100,16 +295,14 @@ func (rc *Client) RefreshToken() error {
 	req.SetBasicAuth(rc.clientId, rc.clientSecret)
 
 	// Send the request
+	if err := rc.rateLimiter.wait(ctx); err != nil {
+		return err
+	}
 	resp, err := retryHttpRequest(rc.httpClient, req, maxRetryAttempts, initialRetryDelay)
 	if err != nil {
 		log.Error().Msgf("Error sending request: %v", err)
 		return err
 	}
-	if resp.StatusCode/100 != 2 {
-		log.Error().Msgf("Error request: %v", resp.Status)
-		return fmt.Errorf("received non OK status code: %s", resp.Status)
-	}
-
 	defer resp.Body.Close()
 
 	var tokenRes tokenRes
@@ -118,20 +311,102 @@ func (rc *Client) RefreshToken() error {
 		log.Error().Err(err).Msg("Error decoding response body")
 		return err
 	}
+	// reddit reports bad credentials with a 200 and an error field
+	if tokenRes.AccessToken == "" {
+		log.Error().Msgf("Error getting token: %s", tokenRes.Error)
+		return fmt.Errorf("%w: token request failed: %s", ErrUnauthorized, tokenRes.Error)
+	}
 
 	rc.accessToken = tokenRes.AccessToken
 	rc.tokenExpireTimeMilli = now.Add(time.Duration(tokenRes.ExpiresIn) * time.Second).UnixMilli()
 
+	if rc.tokenStore != nil {
+		token := Token{
+			AccessToken: rc.accessToken,
+			Expiry:      time.UnixMilli(rc.tokenExpireTimeMilli),
+			GrantType:   rc.grantType,
+			Username:    rc.Username(),
+		}
+		if err := rc.tokenStore.Save(token); err != nil {
+			// the token is still usable, it just will not survive a restart
+			log.Error().Err(err).Msg("Error saving token")
+		}
+	}
 	return nil
 }
 
-func (rc *Client) newRequest(method string, url string, body io.Reader) (*http.Request, error) {
-	err := rc.RefreshToken()
+// checkResponse returns an *APIError for a non 2xx response. A 401 also
+// invalidates the cached token so the next request fetches a new one.
+func (rc *Client) checkResponse(resp *http.Response) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	log.Error().Msgf("Error request: %v", resp.Status)
+	if resp.StatusCode == http.StatusUnauthorized {
+		rc.mu.Lock()
+		rc.tokenExpireTimeMilli = 0
+		rc.mu.Unlock()
+	}
+	return newAPIError(resp)
+}
+
+// jsonResponse is the envelope of api_type=json responses.
+type jsonResponse[T any] struct {
+	JSON struct {
+		Errors    [][]string `json:"errors"`
+		Ratelimit float64    `json:"ratelimit"`
+		Data      T          `json:"data"`
+	} `json:"json"`
+}
+
+// postForm posts data with api_type=json to path and decodes the response
+// data into v, which may be nil. Errors listed in the body are returned as
+// *SubmitError, joined when there is more than one.
+func (rc *Client) postForm(ctx context.Context, path string, data url.Values, v any) error {
+	data.Set("api_type", "json")
+	req, err := rc.newRequest(ctx, "POST", rc.baseURL+path, strings.NewReader(data.Encode()))
+	if err != nil {
+		log.Error().Err(err).Msg("Error creating request")
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := rc.do(req)
+	if err != nil {
+		log.Error().Msgf("Error sending request: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+	if err := rc.checkResponse(resp); err != nil {
+		return err
+	}
+
+	var res jsonResponse[json.RawMessage]
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		log.Error().Err(err).Msg("Error decoding response body")
+		return err
+	}
+	if len(res.JSON.Errors) > 0 {
+		errs := make([]error, 0, len(res.JSON.Errors))
+		for _, e := range res.JSON.Errors {
+			errs = append(errs, newSubmitError(e, res.JSON.Ratelimit))
+		}
+		log.Error().Msgf("Error response from %s: %v", path, errs)
+		return errors.Join(errs...)
+	}
+	if v == nil || len(res.JSON.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(res.JSON.Data, v)
+}
+
+func (rc *Client) newRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Request, error) {
+	err := rc.RefreshToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -143,20 +418,49 @@ func (rc *Client) newRequest(method string, url string, body io.Reader) (*http.R
 	return req, nil
 }
 
+// retryHttpRequest sends req until it succeeds or attempts run out, doubling
+// the delay after every failure. A 429 response waits for its Retry-After
+// instead, and other 4xx responses are returned as an *APIError without retrying.
+// Waiting stops early when the request context is cancelled.
 func retryHttpRequest(client *http.Client, req *http.Request, attempts int, sleep time.Duration) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-
+	var lastErr error
 	for i := 0; i < attempts; i++ {
-		resp, err = client.Do(req)
+		if i > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		delay := sleep
+		resp, err := client.Do(req)
 		if err == nil && resp.StatusCode/100 == 2 {
 			return resp, nil
 		}
+		if err != nil {
+			log.Error().Msgf("Error sending request: %v", err)
+			lastErr = err
+		} else {
+			log.Error().Msgf("Error request: %v", resp.Status)
+			apiErr := newAPIError(resp)
+			resp.Body.Close()
+			if !apiErr.Retryable() {
+				return nil, apiErr
+			}
+			if retryAfter, ok := parseRetryAfter(resp.Header, time.Now()); ok && resp.StatusCode == http.StatusTooManyRequests {
+				delay = retryAfter
+			}
+			lastErr = apiErr
+		}
 
-		log.Error().Msgf("Error sending request: %v", err)
-		time.Sleep(sleep)
+		if i < attempts-1 {
+			if err := sleepContext(req.Context(), delay); err != nil {
+				return nil, err
+			}
+		}
 		sleep *= 2 // increase delay exponentially
 	}
 
-	return nil, fmt.Errorf("http request exceeded %d retry attempts", attempts)
+	return nil, fmt.Errorf("http request exceeded %d retry attempts: %w", attempts, lastErr)
 }
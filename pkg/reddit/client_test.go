@@ -1,6 +1,8 @@
 package reddit
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -132,3 +134,169 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"test-token","token_type":"bearer","expires_in":86400,"scope":"*"}`))
+	})
+	mux.HandleFunc("/", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	rc, err := NewRedditClient("id", "secret", "test-user", "password", "", 0, false,
+		WithBaseURL(server.URL),
+		WithAuthURL(server.URL+"/api/v1/access_token"),
+		WithHTTPClient(server.Client()),
+	)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	return rc
+}
+
+func TestClientOptions(t *testing.T) {
+	var gotAuth, gotPath string
+	rc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotPath = r.URL.Path
+		switch r.URL.Path {
+		case "/r/buildapcsales/new":
+			w.Write([]byte(`{"kind":"Listing","data":{"children":[{"kind":"t3","data":{"id":"abc","title":"[SSD] test","name":"t3_abc"}}]}}`))
+		case "/comments/abc":
+			w.Write([]byte(`[{"kind":"Listing","data":{"children":[]}},{"kind":"Listing","data":{"children":[{"kind":"t1","data":{"id":"c1","author":"SSDBot"}}]}}]`))
+		case "/user/test-user/comments":
+			w.Write([]byte(`{"kind":"Listing","data":{"children":[{"kind":"t1","data":{"id":"c2","link_id":"t3_abc"}}]}}`))
+		case "/api/comment":
+			w.Write([]byte(`{"json":{"errors":[],"data":{"things":[{"kind":"t1","data":{"id":"c3","name":"t1_c3"}}]}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	submissions, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25)
+	if err != nil || len(submissions) != 1 || submissions[0].ID != "abc" {
+		t.Fatalf("GetNewSubmissions() = %v, %v", submissions, err)
+	}
+	if gotAuth != "bearer test-token" {
+		t.Errorf("Authorization header = %q, want %q", gotAuth, "bearer test-token")
+	}
+
+	comments, err := rc.GetCommentsBySubmissionId(context.Background(), "abc", 100)
+	if err != nil || len(comments) != 1 || comments[0].Author != "SSDBot" {
+		t.Fatalf("GetCommentsBySubmissionId() = %v, %v", comments, err)
+	}
+
+	userComments, err := rc.GetUserNewestComments(context.Background(), 25)
+	if err != nil || len(userComments) != 1 || userComments[0].LinkID != "t3_abc" {
+		t.Fatalf("GetUserNewestComments() = %v, %v", userComments, err)
+	}
+
+	if _, err := rc.SubmitComment(context.Background(), "abc", "hello"); err != nil {
+		t.Fatalf("SubmitComment() unexpected error = %v", err)
+	}
+	if gotPath != "/api/comment" {
+		t.Errorf("SubmitComment() path = %q, want /api/comment", gotPath)
+	}
+}
+
+func TestRetryHttpRequestContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+
+	start := time.Now()
+	_, err := retryHttpRequest(&http.Client{}, req, 5, time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("retryHttpRequest() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("retryHttpRequest() kept sleeping after the context was done")
+	}
+}
+
+func TestClientContextCancelled(t *testing.T) {
+	rc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"kind":"Listing","data":{"children":[]}}`))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rc.GetNewSubmissions(ctx, "buildapcsales", 25); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetNewSubmissions() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewRedditClientGrantValidation(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientSecret string
+		username     string
+		opt          Option
+		errContains  string
+	}{
+		{
+			name:         "refresh token grant without refresh token",
+			clientSecret: "",
+			username:     "bot",
+			opt:          WithRefreshToken(""),
+			errContains:  "refresh token",
+		},
+		{
+			name:         "refresh token grant without username",
+			clientSecret: "",
+			opt:          WithRefreshToken("refresh"),
+			errContains:  "username",
+		},
+		{
+			name:         "client credentials without client secret",
+			clientSecret: "",
+			opt:          WithClientCredentials(),
+			errContains:  "clientSecret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRedditClient("test-client-id", tt.clientSecret, tt.username, "", "", 0, false, tt.opt)
+			if err == nil || !contains(err.Error(), tt.errContains) {
+				t.Errorf("NewRedditClient() error = %v, should contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestGetNewSubmissionsFields(t *testing.T) {
+	var rawJson string
+	rc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		rawJson = r.URL.Query().Get("raw_json")
+		w.Write([]byte(`{"kind":"Listing","data":{"children":[{"kind":"t3","data":{
+			"id":"abc","name":"t3_abc","title":"[SSD] WD Black SN850X 2TB & heatsink",
+			"url":"https://www.amazon.com/dp/B0B7CMZ3QH","domain":"amazon.com",
+			"permalink":"/r/buildapcsales/comments/abc/ssd_wd_black/","selftext":"",
+			"created_utc":1700000000.5,"locked":true,"archived":false,
+			"removed_by_category":"moderator","over_18":false,"stickied":true}}]}}`))
+	})
+
+	submissions, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25)
+	if err != nil || len(submissions) != 1 {
+		t.Fatalf("GetNewSubmissions() = %v, %v", submissions, err)
+	}
+	if rawJson != "1" {
+		t.Errorf("raw_json = %q, want 1", rawJson)
+	}
+	s := submissions[0]
+	if s.Title != "[SSD] WD Black SN850X 2TB & heatsink" || s.Domain != "amazon.com" || s.URL != "https://www.amazon.com/dp/B0B7CMZ3QH" {
+		t.Errorf("GetNewSubmissions() = %+v, want title, domain and url decoded", s)
+	}
+	if !s.Locked || s.Archived || !s.Stickied || s.Over18 || !s.IsRemoved() {
+		t.Errorf("GetNewSubmissions() = %+v, want locked, stickied and removed", s)
+	}
+	if want := time.Unix(1700000000, 5e8); !s.Created().Equal(want) {
+		t.Errorf("Created() = %v, want %v", s.Created(), want)
+	}
+}
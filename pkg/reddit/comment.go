@@ -0,0 +1,42 @@
+package reddit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// EditComment replaces the text of a comment made by the authenticated user
+// and returns the updated comment. fullname is the comment fullname, e.g. t1_abc.
+// It needs a user context.
+func (rc *Client) EditComment(ctx context.Context, fullname, text string) (*SubmissionComment, error) {
+	if err := rc.requireUserContext(); err != nil {
+		return nil, err
+	}
+	data := url.Values{}
+	data.Set("thing_id", fullname)
+	data.Set("text", text)
+
+	var res struct {
+		Things []Children[SubmissionComment] `json:"things"`
+	}
+	err := rc.postForm(ctx, "/api/editusertext", data, &res)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Things) == 0 {
+		return nil, fmt.Errorf("invalid response format: no comment returned")
+	}
+	return &res.Things[0].Data, nil
+}
+
+// DeleteComment deletes a comment made by the authenticated user.
+// fullname is the comment fullname, e.g. t1_abc. It needs a user context.
+func (rc *Client) DeleteComment(ctx context.Context, fullname string) error {
+	if err := rc.requireUserContext(); err != nil {
+		return err
+	}
+	data := url.Values{}
+	data.Set("id", fullname)
+	return rc.postForm(ctx, "/api/del", data, nil)
+}
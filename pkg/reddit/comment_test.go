@@ -0,0 +1,81 @@
+package reddit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+func TestEditAndDeleteComment(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] test"})
+	other := fake.AddComment(submission.ID, "someone", "not yours")
+
+	posted, err := rc.SubmitComment(context.Background(), submission.ID, "old specs")
+	if err != nil {
+		t.Fatalf("SubmitComment() unexpected error = %v", err)
+	}
+
+	edited, err := rc.EditComment(context.Background(), posted.Name, "new specs")
+	if err != nil {
+		t.Fatalf("EditComment() unexpected error = %v", err)
+	}
+	if edited.Name != posted.Name || edited.Body != "new specs" {
+		t.Errorf("EditComment() = %+v, want %s with the new text", edited, posted.Name)
+	}
+	if stored := fake.CommentsBy("bot"); len(stored) != 1 || stored[0].Body != "new specs" {
+		t.Errorf("CommentsBy() = %v, want the edited comment", stored)
+	}
+
+	if _, err := rc.EditComment(context.Background(), other.Name, "hijacked"); !errors.Is(err, reddit.ErrForbidden) {
+		t.Errorf("EditComment() on another user's comment error = %v, want %v", err, reddit.ErrForbidden)
+	}
+	if _, err := rc.EditComment(context.Background(), "t1_missing", "text"); !errors.Is(err, reddit.ErrNotFound) {
+		t.Errorf("EditComment() on a missing comment error = %v, want %v", err, reddit.ErrNotFound)
+	}
+
+	if err := rc.DeleteComment(context.Background(), posted.Name); err != nil {
+		t.Fatalf("DeleteComment() unexpected error = %v", err)
+	}
+	if stored := fake.CommentsBy("bot"); len(stored) != 0 {
+		t.Errorf("CommentsBy() = %v, want no comments after delete", stored)
+	}
+}
+
+func TestCommentedBy(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] busy thread"})
+	for i := 0; i < 250; i++ {
+		fake.AddComment(submission.ID, "someone", "nice price")
+	}
+	fake.AddComment(submission.ID, "SSDBot", "specs")
+
+	comments, more, err := rc.GetSubmissionComments(context.Background(), submission.ID, 100)
+	if err != nil {
+		t.Fatalf("GetSubmissionComments() unexpected error = %v", err)
+	}
+	if len(comments) != 100 || len(more) != 151 {
+		t.Errorf("GetSubmissionComments() returned %d comments and %d more, want 100 and 151", len(comments), len(more))
+	}
+
+	got, err := rc.CommentedBy(context.Background(), submission.ID, "OtherBot", "ssdbot")
+	if err != nil || got != "SSDBot" {
+		t.Errorf("CommentedBy() = %q, %v, want %q", got, err, "SSDBot")
+	}
+	if got := fake.Requests("/api/morechildren"); got != 2 {
+		t.Errorf("CommentedBy() made %d more children requests, want 2", got)
+	}
+
+	commented, err := rc.IsCommentedByUser(context.Background(), submission.ID, "OtherBot")
+	if err != nil || commented {
+		t.Errorf("IsCommentedByUser() = %v, %v, want false, nil", commented, err)
+	}
+
+	fake.InjectStatus("/comments/"+submission.ID, http.StatusInternalServerError, 1)
+	if _, err := rc.IsCommentedByUser(context.Background(), submission.ID, "SSDBot"); !errors.Is(err, reddit.ErrServer) {
+		t.Errorf("IsCommentedByUser() error = %v, want %v", err, reddit.ErrServer)
+	}
+}
@@ -0,0 +1,179 @@
+package reddit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBodySize caps how much of an error response body is read.
+const maxErrorBodySize = 4 << 10
+
+// Sentinel errors matched with errors.Is against errors returned by Client.
+var (
+	ErrUnauthorized = errors.New("reddit: unauthorized")
+	ErrForbidden    = errors.New("reddit: forbidden")
+	ErrRateLimited  = errors.New("reddit: rate limited")
+	ErrNotFound     = errors.New("reddit: not found")
+	ErrThreadLocked = errors.New("reddit: thread locked")
+	ErrServer       = errors.New("reddit: server error")
+
+	// ErrUserContextRequired is returned by methods that act as a Reddit user
+	// when the client uses application only credentials.
+	ErrUserContextRequired = errors.New("reddit: user context required")
+)
+
+// APIError is returned when Reddit responds with a non 2xx status code.
+// Use errors.As to get the details, or errors.Is with one of the sentinels.
+type APIError struct {
+	StatusCode int
+	Status     string
+	// Reason and Message are taken from the response body when present,
+	// e.g. reason "banned" or "private" on a 403.
+	Reason  string
+	Message string
+	// RetryAfter is set from the Retry-After header on a 429.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("received non OK status code: %s", e.Status)
+	if e.Reason != "" {
+		msg += ", reason: " + e.Reason
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", retry after %v", e.RetryAfter)
+	}
+	return msg
+}
+
+// Unwrap returns the sentinel error matching the status code, or nil.
+func (e *APIError) Unwrap() error {
+	switch {
+	case strings.EqualFold(e.Reason, "THREAD_LOCKED"):
+		return ErrThreadLocked
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// Retryable reports whether sending the same request again may succeed.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+// SubmitError is an error listed in the body of an api_type=json response,
+// e.g. ["RATELIMIT", "you are doing that too much. try again in 9 minutes.", "ratelimit"].
+type SubmitError struct {
+	Code    string
+	Message string
+	Field   string
+	// RetryAfter is the wait parsed from a RATELIMIT error.
+	RetryAfter time.Duration
+}
+
+var retryAfterPattern = regexp.MustCompile(`try again in (\d+) (millisecond|second|minute|hour)`)
+
+// newSubmitError builds a SubmitError from a [code, message, field] triple.
+// ratelimit is the wait in seconds Reddit may send along a RATELIMIT error.
+func newSubmitError(e []string, ratelimit float64) *SubmitError {
+	submitErr := &SubmitError{}
+	if len(e) > 0 {
+		submitErr.Code = e[0]
+	}
+	if len(e) > 1 {
+		submitErr.Message = e[1]
+	}
+	if len(e) > 2 {
+		submitErr.Field = e[2]
+	}
+	if submitErr.Code != "RATELIMIT" {
+		return submitErr
+	}
+	if ratelimit > 0 {
+		submitErr.RetryAfter = time.Duration(ratelimit * float64(time.Second))
+	} else if match := retryAfterPattern.FindStringSubmatch(submitErr.Message); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		units := map[string]time.Duration{
+			"millisecond": time.Millisecond,
+			"second":      time.Second,
+			"minute":      time.Minute,
+			"hour":        time.Hour,
+		}
+		submitErr.RetryAfter = time.Duration(n) * units[match[2]]
+	}
+	return submitErr
+}
+
+func (e *SubmitError) Error() string {
+	return fmt.Sprintf("reddit api error %s: %s", e.Code, e.Message)
+}
+
+// Is matches the sentinel errors for the codes Reddit is known to send.
+func (e *SubmitError) Is(target error) bool {
+	switch e.Code {
+	case "RATELIMIT":
+		return target == ErrRateLimited
+	case "THREAD_LOCKED", "TOO_OLD":
+		return target == ErrThreadLocked
+	case "DELETED_LINK", "DELETED_COMMENT", "NO_THING_ID":
+		return target == ErrNotFound
+	case "USER_REQUIRED":
+		return target == ErrUnauthorized
+	case "SUBREDDIT_NOTALLOWED", "SUBREDDIT_NOEXIST", "BANNED_FROM_SUBREDDIT", "NOT_AUTHOR":
+		return target == ErrForbidden
+	}
+	return false
+}
+
+// RetryAfter returns how long to wait before retrying when err is a rate
+// limit error that carries a wait duration.
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, true
+	}
+	var submitErr *SubmitError
+	if errors.As(err, &submitErr) && submitErr.RetryAfter > 0 {
+		return submitErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// newAPIError builds an APIError from a non 2xx response. It consumes, but
+// does not close, the response body.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	if d, ok := parseRetryAfter(resp.Header, time.Now()); ok {
+		apiErr.RetryAfter = d
+	}
+
+	var body struct {
+		Message string `json:"message"`
+		Reason  string `json:"reason"`
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err == nil && json.Unmarshal(data, &body) == nil {
+		apiErr.Reason = body.Reason
+		apiErr.Message = body.Message
+	}
+	return apiErr
+}
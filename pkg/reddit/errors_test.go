@@ -0,0 +1,147 @@
+package reddit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header map[string]string
+		body   string
+		want   error
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized, want: ErrUnauthorized},
+		{name: "banned", status: http.StatusForbidden, body: `{"reason": "banned", "message": "Forbidden", "error": 403}`, want: ErrForbidden},
+		{name: "thread locked", status: http.StatusForbidden, body: `{"reason": "THREAD_LOCKED", "message": "Forbidden", "error": 403}`, want: ErrThreadLocked},
+		{name: "not found", status: http.StatusNotFound, want: ErrNotFound},
+		{name: "rate limited", status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "1"}, want: ErrRateLimited},
+		{name: "server error", status: http.StatusBadGateway, want: ErrServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			_, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("GetNewSubmissions() error = %v, want %v", err, tt.want)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("GetNewSubmissions() error = %v, want *APIError with status %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 9 * time.Minute}
+	if d, ok := RetryAfter(err); !ok || d != 9*time.Minute {
+		t.Errorf("RetryAfter() = %v, %v, want 9m, true", d, ok)
+	}
+	if _, ok := RetryAfter(errors.New("other")); ok {
+		t.Error("RetryAfter() ok for an unrelated error")
+	}
+}
+
+func TestRetryHttpRequestNonRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int
+		want      error
+	}{
+		{name: "forbidden is not retried", status: http.StatusForbidden, wantCalls: 1, want: ErrForbidden},
+		{name: "bad request is not retried", status: http.StatusBadRequest, wantCalls: 1},
+		{name: "server error is retried", status: http.StatusInternalServerError, wantCalls: 3, want: ErrServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callCount := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				callCount++
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			_, err := retryHttpRequest(&http.Client{}, req, 3, time.Millisecond)
+			if err == nil {
+				t.Fatal("retryHttpRequest() expected error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("retryHttpRequest() error = %v, want %v", err, tt.want)
+			}
+			if callCount != tt.wantCalls {
+				t.Errorf("retryHttpRequest() sent %d requests, want %d", callCount, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSubmitCommentErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		want           error
+		wantCode       string
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:           "rate limit in message",
+			body:           `{"json":{"errors":[["RATELIMIT","you are doing that too much. try again in 9 minutes.","ratelimit"]]}}`,
+			want:           ErrRateLimited,
+			wantCode:       "RATELIMIT",
+			wantRetryAfter: 9 * time.Minute,
+		},
+		{
+			name:           "rate limit in seconds field",
+			body:           `{"json":{"ratelimit":30.5,"errors":[["RATELIMIT","you are doing that too much. try again in 30 seconds.","ratelimit"]]}}`,
+			want:           ErrRateLimited,
+			wantCode:       "RATELIMIT",
+			wantRetryAfter: 30500 * time.Millisecond,
+		},
+		{
+			name:     "thread locked",
+			body:     `{"json":{"errors":[["THREAD_LOCKED","that comment thread has been locked", null]]}}`,
+			want:     ErrThreadLocked,
+			wantCode: "THREAD_LOCKED",
+		},
+		{
+			name:     "deleted link",
+			body:     `{"json":{"errors":[["DELETED_LINK","the link you are commenting on has been deleted","parent"]]}}`,
+			want:     ErrNotFound,
+			wantCode: "DELETED_LINK",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			})
+			comment, err := rc.SubmitComment(context.Background(), "abc", "hello")
+			if comment != nil || !errors.Is(err, tt.want) {
+				t.Fatalf("SubmitComment() = %v, %v, want error %v", comment, err, tt.want)
+			}
+			var submitErr *SubmitError
+			if !errors.As(err, &submitErr) || submitErr.Code != tt.wantCode {
+				t.Fatalf("SubmitComment() error = %v, want *SubmitError with code %s", err, tt.wantCode)
+			}
+			retryAfter, _ := RetryAfter(err)
+			if retryAfter != tt.wantRetryAfter {
+				t.Errorf("RetryAfter() = %v, want %v", retryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package reddit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Mailbox is a folder of the authenticated user's inbox.
+type Mailbox string
+
+const (
+	MailboxInbox          Mailbox = "inbox"
+	MailboxUnread         Mailbox = "unread"
+	MailboxMentions       Mailbox = "mentions"
+	MailboxCommentReplies Mailbox = "comments"
+	MailboxMessages       Mailbox = "messages"
+)
+
+// Message types reported by Reddit for inbox items that are comments.
+const (
+	MessageUsernameMention = "username_mention"
+	MessageCommentReply    = "comment_reply"
+	MessagePostReply       = "post_reply"
+)
+
+// Message is an item of the inbox: a comment mentioning or replying to the
+// user, or a private message.
+type Message struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Author    string `json:"author"`
+	Dest      string `json:"dest"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	Subreddit string `json:"subreddit"`
+	ParentID  string `json:"parent_id"`
+	LinkTitle string `json:"link_title"`
+	// Context is the permalink of a comment, empty for private messages.
+	Context    string  `json:"context"`
+	Type       string  `json:"type"`
+	WasComment bool    `json:"was_comment"`
+	New        bool    `json:"new"`
+	CreatedUTC float64 `json:"created_utc"`
+}
+
+// Created returns the time the message was sent.
+func (m Message) Created() time.Time {
+	return Submission{CreatedUTC: m.CreatedUTC}.Created()
+}
+
+// GetUnreadMessages fetches the newest unread items of the inbox.
+// It needs a user context.
+func (rc *Client) GetUnreadMessages(ctx context.Context, limit int) ([]Message, error) {
+	listing, err := rc.GetMessagesPage(ctx, MailboxUnread, ListingOptions{Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return listing.Items(), nil
+}
+
+// Messages returns a paginator over a mailbox, newest first, fetching at
+// most maxPages pages.
+func (rc *Client) Messages(mailbox Mailbox, opts ListingOptions, maxPages int) *Paginator[Message] {
+	return NewPaginator(func(ctx context.Context, opts ListingOptions) (Listing[Message], error) {
+		return rc.GetMessagesPage(ctx, mailbox, opts)
+	}, opts, maxPages)
+}
+
+// GetMessagesPage fetches a single page of a mailbox. Reading a mailbox does
+// not mark its items as read, use MarkRead for that.
+// It needs a user context.
+func (rc *Client) GetMessagesPage(ctx context.Context, mailbox Mailbox, opts ListingOptions) (Listing[Message], error) {
+	if err := rc.requireUserContext(); err != nil {
+		return Listing[Message]{}, err
+	}
+	return getListing[Message](ctx, rc, fmt.Sprintf("/message/%s", mailbox), opts)
+}
+
+// MarkRead marks inbox items as read. fullnames are message or comment
+// fullnames, e.g. t1_abc or t4_abc. It needs a user context.
+func (rc *Client) MarkRead(ctx context.Context, fullnames ...string) error {
+	if err := rc.requireUserContext(); err != nil {
+		return err
+	}
+	if len(fullnames) == 0 {
+		return nil
+	}
+	data := url.Values{}
+	data.Set("id", strings.Join(fullnames, ","))
+	return rc.postForm(ctx, "/api/read_message", data, nil)
+}
+
+// SendMessage sends a private message to a user.
+// Errors reported in the response body are returned as *SubmitError.
+// It needs a user context.
+func (rc *Client) SendMessage(ctx context.Context, to, subject, text string) error {
+	if err := rc.requireUserContext(); err != nil {
+		return err
+	}
+	data := url.Values{}
+	data.Set("to", to)
+	data.Set("subject", subject)
+	data.Set("text", text)
+	return rc.postForm(ctx, "/api/compose", data, nil)
+}
@@ -0,0 +1,98 @@
+package reddit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+func TestInbox(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapc", Title: "Which SSD?"})
+	posted, err := rc.SubmitComment(context.Background(), submission.ID, "specs")
+	if err != nil {
+		t.Fatalf("SubmitComment() unexpected error = %v", err)
+	}
+	mention := fake.AddComment(submission.ID, "alice", "what about the /u/bot wd sn850x?")
+	mention2 := fake.AddComment(submission.ID, "alice", "u/bot samsung 990 pro")
+	reply := fake.AddReply(posted.Name, "carol", "thanks")
+	fake.AddComment(submission.ID, "dave", "u/someone_else hello")
+
+	unread, err := rc.GetUnreadMessages(context.Background(), 25)
+	if err != nil {
+		t.Fatalf("GetUnreadMessages() unexpected error = %v", err)
+	}
+	if len(unread) != 3 || unread[0].Name != reply.Name || unread[2].Name != mention.Name {
+		t.Fatalf("GetUnreadMessages() = %+v, want the reply and both mentions, newest first", unread)
+	}
+	if got := unread[0]; got.Type != reddit.MessageCommentReply || !got.WasComment || !got.New || got.ParentID != posted.Name {
+		t.Errorf("GetUnreadMessages()[0] = %+v, want an unread comment reply to %s", got, posted.Name)
+	}
+	if got := unread[2]; got.Type != reddit.MessageUsernameMention || got.Author != "alice" || got.LinkTitle != "Which SSD?" {
+		t.Errorf("GetUnreadMessages()[2] = %+v, want a mention by alice", got)
+	}
+
+	mentions, err := rc.Messages(reddit.MailboxMentions, reddit.ListingOptions{Limit: 1}, 5).All(context.Background())
+	if err != nil {
+		t.Fatalf("Messages() unexpected error = %v", err)
+	}
+	if len(mentions) != 2 {
+		t.Errorf("Messages(%q) returned %d messages, want 2", reddit.MailboxMentions, len(mentions))
+	}
+
+	if err := rc.MarkRead(context.Background(), mention.Name, mention2.Name); err != nil {
+		t.Fatalf("MarkRead() unexpected error = %v", err)
+	}
+	unread, err = rc.GetUnreadMessages(context.Background(), 25)
+	if err != nil {
+		t.Fatalf("GetUnreadMessages() unexpected error = %v", err)
+	}
+	if len(unread) != 1 || unread[0].Name != reply.Name {
+		t.Errorf("GetUnreadMessages() after MarkRead() = %+v, want only %s", unread, reply.Name)
+	}
+}
+
+func TestReply(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	submission := fake.AddSubmission(reddit.Submission{Subreddit: "buildapc", Title: "Which SSD?"})
+	parent := fake.AddComment(submission.ID, "alice", "u/bot wd sn850x")
+
+	reply, err := rc.Reply(context.Background(), parent.Name, "specs")
+	if err != nil {
+		t.Fatalf("Reply() unexpected error = %v", err)
+	}
+	if reply.ParentID != parent.Name || reply.LinkID != submission.Name {
+		t.Errorf("Reply() = %+v, want a reply to %s on %s", reply, parent.Name, submission.Name)
+	}
+	if unread := fake.Unread("alice"); len(unread) != 1 || unread[0].Name != reply.Name {
+		t.Errorf("Unread(alice) = %+v, want the reply", unread)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	received := fake.AddMessage("alice", "bot", "lookup", "lookup wd sn850x")
+
+	messages, err := rc.Messages(reddit.MailboxMessages, reddit.ListingOptions{}, 1).All(context.Background())
+	if err != nil {
+		t.Fatalf("Messages() unexpected error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].Name != received.Name || messages[0].WasComment || messages[0].Body != "lookup wd sn850x" {
+		t.Fatalf("Messages(%q) = %+v, want the private message from alice", reddit.MailboxMessages, messages)
+	}
+
+	if err := rc.SendMessage(context.Background(), "alice", "re: lookup", "specs"); err != nil {
+		t.Fatalf("SendMessage() unexpected error = %v", err)
+	}
+	sent := fake.MessagesTo("alice")
+	if len(sent) != 1 || sent[0].Author != "bot" || sent[0].Subject != "re: lookup" || sent[0].Body != "specs" {
+		t.Errorf("MessagesTo(alice) = %+v, want the message sent by the bot", sent)
+	}
+
+	var submitErr *reddit.SubmitError
+	if err := rc.SendMessage(context.Background(), "alice", "empty", ""); !errors.As(err, &submitErr) || submitErr.Code != "NO_TEXT" {
+		t.Errorf("SendMessage() without text error = %v, want a NO_TEXT error", err)
+	}
+}
@@ -0,0 +1,37 @@
+package reddit
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// MaxInfoNames is the number of fullnames Reddit accepts in a single info request.
+const MaxInfoNames = 100
+
+// GetSubmissionsByName fetches submissions by fullname, e.g. t3_abc, in a
+// single request. Fullnames that do not exist are left out of the result.
+func (rc *Client) GetSubmissionsByName(ctx context.Context, fullnames ...string) ([]Submission, error) {
+	return getInfo[Submission](ctx, rc, fullnames)
+}
+
+// GetCommentsByName fetches comments by fullname, e.g. t1_abc, in a single
+// request. Fullnames that do not exist are left out of the result.
+func (rc *Client) GetCommentsByName(ctx context.Context, fullnames ...string) ([]SubmissionComment, error) {
+	return getInfo[SubmissionComment](ctx, rc, fullnames)
+}
+
+// getInfo fetches things of the same kind by fullname with /api/info.
+func getInfo[T any](ctx context.Context, rc *Client, fullnames []string) ([]T, error) {
+	if len(fullnames) == 0 {
+		return nil, nil
+	}
+	query := url.Values{}
+	query.Set("raw_json", "1")
+	query.Set("id", strings.Join(fullnames, ","))
+	listing, err := getListingQuery[T](ctx, rc, "/api/info", query)
+	if err != nil {
+		return nil, err
+	}
+	return listing.Items(), nil
+}
@@ -0,0 +1,35 @@
+package reddit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+func TestGetByName(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	first := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] first", Author: "alice"})
+	second := fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] second", Author: "bob"})
+	comment := fake.AddComment(first.ID, "carol", "nice price")
+
+	submissions, err := rc.GetSubmissionsByName(context.Background(), second.Name, "t3_missing", first.Name)
+	if err != nil {
+		t.Fatalf("GetSubmissionsByName() unexpected error = %v", err)
+	}
+	if len(submissions) != 2 || submissions[0].Title != second.Title || submissions[1].Author != "alice" {
+		t.Errorf("GetSubmissionsByName() = %+v, want the second and first submission", submissions)
+	}
+
+	comments, err := rc.GetCommentsByName(context.Background(), comment.Name)
+	if err != nil {
+		t.Fatalf("GetCommentsByName() unexpected error = %v", err)
+	}
+	if len(comments) != 1 || comments[0].Body != "nice price" || comments[0].LinkID != first.Name {
+		t.Errorf("GetCommentsByName() = %+v, want the comment by carol", comments)
+	}
+
+	if got, err := rc.GetCommentsByName(context.Background()); err != nil || got != nil {
+		t.Errorf("GetCommentsByName() without names = %v, %v, want nil, nil", got, err)
+	}
+}
@@ -1,5 +1,15 @@
 package reddit
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
 // Listing represents a Reddit API listing response.
 type Listing[T any] struct {
 	Kind string         `json:"kind"`
@@ -21,3 +31,129 @@ type Children[T any] struct {
 	Kind string `json:"kind"`
 	Data T      `json:"data"`
 }
+
+// Items returns the data of every child in the listing.
+func (l Listing[T]) Items() []T {
+	var items []T
+	for _, child := range l.Data.Children {
+		items = append(items, child.Data)
+	}
+	return items
+}
+
+// ListingOptions are the paging parameters accepted by listing endpoints.
+// After and Before are fullnames (e.g. t3_abc) used as cursors.
+type ListingOptions struct {
+	Limit  int
+	After  string
+	Before string
+}
+
+func (opts ListingOptions) values() url.Values {
+	v := url.Values{}
+	// return text as is instead of html escaped, e.g. & instead of &amp;
+	v.Set("raw_json", "1")
+	if opts.Limit > 0 {
+		v.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.After != "" {
+		v.Set("after", opts.After)
+	}
+	if opts.Before != "" {
+		v.Set("before", opts.Before)
+	}
+	return v
+}
+
+// getListing fetches a single listing page from path relative to the base url.
+func getListing[T any](ctx context.Context, rc *Client, path string, opts ListingOptions) (Listing[T], error) {
+	return getListingQuery[T](ctx, rc, path, opts.values())
+}
+
+// getListingQuery fetches a listing from path relative to the base url with
+// the given query parameters.
+func getListingQuery[T any](ctx context.Context, rc *Client, path string, query url.Values) (Listing[T], error) {
+	var listing Listing[T]
+	redditUrl := fmt.Sprintf("%s%s?%s", rc.baseURL, path, query.Encode())
+	req, err := rc.newRequest(ctx, "GET", redditUrl, nil)
+	if err != nil {
+		log.Error().Msgf("Error creating request: %v", err)
+		return listing, err
+	}
+	resp, err := rc.do(req)
+	if err != nil {
+		log.Error().Msgf("Error sending request: %v", err)
+		return listing, err
+	}
+	defer resp.Body.Close()
+	if err := rc.checkResponse(resp); err != nil {
+		return listing, err
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&listing)
+	if err != nil {
+		log.Error().Err(err).Msg("Error decoding response body")
+		return listing, err
+	}
+	return listing, nil
+}
+
+// Paginator walks a listing page by page. It follows the after cursor, or the
+// before cursor when the initial options only set Before.
+type Paginator[T any] struct {
+	fetch    func(ctx context.Context, opts ListingOptions) (Listing[T], error)
+	opts     ListingOptions
+	backward bool
+	maxPages int
+	pages    int
+	done     bool
+}
+
+// NewPaginator creates a paginator that calls fetch for every page, starting
+// from opts. A maxPages of zero or less means no limit.
+func NewPaginator[T any](fetch func(ctx context.Context, opts ListingOptions) (Listing[T], error), opts ListingOptions, maxPages int) *Paginator[T] {
+	return &Paginator[T]{
+		fetch:    fetch,
+		opts:     opts,
+		backward: opts.Before != "" && opts.After == "",
+		maxPages: maxPages,
+	}
+}
+
+// HasNext reports whether another page can be fetched.
+func (p *Paginator[T]) HasNext() bool {
+	return !p.done && (p.maxPages <= 0 || p.pages < p.maxPages)
+}
+
+// Next fetches the next page and advances the cursor.
+func (p *Paginator[T]) Next(ctx context.Context) ([]T, error) {
+	listing, err := p.fetch(ctx, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	p.pages++
+	if p.backward {
+		p.opts.Before = listing.Data.Before
+		p.done = listing.Data.Before == ""
+	} else {
+		p.opts.After = listing.Data.After
+		p.done = listing.Data.After == ""
+	}
+	if len(listing.Data.Children) == 0 {
+		p.done = true
+	}
+	return listing.Items(), nil
+}
+
+// All fetches every remaining page and returns the concatenated items.
+func (p *Paginator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for p.HasNext() {
+		page, err := p.Next(ctx)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, page...)
+	}
+	return items, nil
+}
@@ -0,0 +1,118 @@
+package reddit_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit/reddittest"
+)
+
+func newFakeClient(t *testing.T) (*reddittest.Server, *reddit.Client) {
+	t.Helper()
+	fake := reddittest.NewServer()
+	t.Cleanup(fake.Close)
+	rc, err := reddit.NewRedditClient("id", "secret", "bot", "password", "", 0, false, fake.Options()...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	return fake, rc
+}
+
+func seedSubmissions(fake *reddittest.Server, n int) {
+	for i := 0; i < n; i++ {
+		fake.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: fmt.Sprintf("[SSD] %d", i)})
+	}
+}
+
+func TestPaginator(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	seedSubmissions(fake, 7)
+
+	tests := []struct {
+		name      string
+		maxPages  int
+		wantItems int
+		wantPages int
+	}{
+		{name: "all pages", maxPages: 0, wantItems: 7, wantPages: 3},
+		{name: "limited depth", maxPages: 2, wantItems: 6, wantPages: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := rc.NewSubmissions("buildapcsales", reddit.ListingOptions{Limit: 3}, tt.maxPages)
+			var items []reddit.Submission
+			gotPages := 0
+			for pages.HasNext() {
+				page, err := pages.Next(context.Background())
+				if err != nil {
+					t.Fatalf("Next() unexpected error = %v", err)
+				}
+				items = append(items, page...)
+				gotPages++
+			}
+			if len(items) != tt.wantItems || gotPages != tt.wantPages {
+				t.Errorf("got %d items in %d pages, want %d items in %d pages", len(items), gotPages, tt.wantItems, tt.wantPages)
+			}
+			seen := map[string]bool{}
+			for _, item := range items {
+				if seen[item.ID] {
+					t.Errorf("submission %s returned twice", item.ID)
+				}
+				seen[item.ID] = true
+			}
+		})
+	}
+}
+
+func TestPaginatorBefore(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	seedSubmissions(fake, 5)
+
+	all, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 100)
+	if err != nil {
+		t.Fatalf("GetNewSubmissions() unexpected error = %v", err)
+	}
+	items, err := rc.NewSubmissions("buildapcsales", reddit.ListingOptions{Limit: 2, Before: all[4].Name}, 0).All(context.Background())
+	if err != nil {
+		t.Fatalf("All() unexpected error = %v", err)
+	}
+	if len(items) != 4 {
+		t.Errorf("All() returned %d submissions newer than the oldest, want 4", len(items))
+	}
+}
+
+func TestSubmissionPoller(t *testing.T) {
+	fake, rc := newFakeClient(t)
+	seedSubmissions(fake, 5)
+	poller := reddit.NewSubmissionPoller(rc, "buildapcsales", 2, 10)
+
+	first, err := poller.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Poll() unexpected error = %v", err)
+	}
+	if len(first) != 5 {
+		t.Fatalf("first Poll() returned %d submissions, want 5", len(first))
+	}
+	if poller.Newest() != first[0].Name {
+		t.Errorf("Newest() = %s, want %s", poller.Newest(), first[0].Name)
+	}
+
+	seedSubmissions(fake, 3)
+	second, err := poller.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Poll() unexpected error = %v", err)
+	}
+	if len(second) != 3 {
+		t.Errorf("second Poll() returned %d submissions, want only the 3 new ones", len(second))
+	}
+
+	third, err := poller.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Poll() unexpected error = %v", err)
+	}
+	if len(third) != 0 {
+		t.Errorf("third Poll() returned %d submissions, want none", len(third))
+	}
+}
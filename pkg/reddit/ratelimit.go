@@ -0,0 +1,163 @@
+package reddit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// minRemainingRequests is the budget below which the client pauses until the
+// rate limit window resets.
+const minRemainingRequests = 2
+
+// maxRetryAfter is the longest Retry-After a request waits for to be sent
+// again. Longer ones are left to the caller, while the next request waits.
+const maxRetryAfter = time.Minute
+
+// RateLimit is the request budget reported by Reddit in the X-Ratelimit-* headers.
+type RateLimit struct {
+	Used      int
+	Remaining float64
+	Reset     time.Time
+}
+
+// Known reports whether Reddit has reported a budget yet.
+func (r RateLimit) Known() bool {
+	return !r.Reset.IsZero()
+}
+
+// rateLimiter tracks the budget from response headers and pauses requests
+// before the budget runs out.
+type rateLimiter struct {
+	mu    sync.Mutex
+	limit RateLimit
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+func (l *rateLimiter) get() RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// wait blocks until the budget allows another request.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	limit := l.limit
+	now := l.now()
+	l.mu.Unlock()
+
+	if !limit.Known() || limit.Remaining >= minRemainingRequests || !now.Before(limit.Reset) {
+		return nil
+	}
+	d := limit.Reset.Sub(now)
+	log.Warn().Msgf("Reddit rate limit almost exhausted (%.0f remaining), pausing for %v", limit.Remaining, d)
+	return l.sleep(ctx, d)
+}
+
+// update records the budget reported by a response. A 429 response exhausts
+// the budget until its Retry-After has passed.
+func (l *rateLimiter) update(resp *http.Response) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if remaining, err := strconv.ParseFloat(resp.Header.Get("X-Ratelimit-Remaining"), 64); err == nil {
+		l.limit.Remaining = remaining
+		if used, err := strconv.Atoi(resp.Header.Get("X-Ratelimit-Used")); err == nil {
+			l.limit.Used = used
+		}
+		if reset, err := strconv.ParseFloat(resp.Header.Get("X-Ratelimit-Reset"), 64); err == nil {
+			l.limit.Reset = now.Add(time.Duration(reset * float64(time.Second)))
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		l.limit.Remaining = 0
+		if d, ok := parseRetryAfter(resp.Header, now); ok {
+			l.limit.Reset = now.Add(d)
+		}
+	}
+}
+
+// rateLimitTransport records the budget from every response. Requests wait
+// for the budget in Client.do instead, as a wait inside the transport would
+// count against the http client timeout.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.limiter.update(resp)
+	return resp, nil
+}
+
+// do sends req once the budget allows it. A request answered with a 429 and
+// a Retry-After of at most maxRetryAfter is sent once more after it has
+// passed, a second 429 is returned to the caller.
+func (rc *Client) do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := rc.rateLimiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := rc.httpClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == 2 {
+			return resp, err
+		}
+		d, ok := parseRetryAfter(resp.Header, time.Now())
+		if !ok || d > maxRetryAfter || req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		log.Warn().Msgf("Too many requests to %s, retrying in %v", req.URL.Path, d)
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
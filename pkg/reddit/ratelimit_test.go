@@ -0,0 +1,219 @@
+package reddit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "seconds", value: "9", want: 9 * time.Second, wantOk: true},
+		{name: "http date", value: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute, wantOk: true},
+		{name: "date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, wantOk: true},
+		{name: "missing", value: "", want: 0, wantOk: false},
+		{name: "garbage", value: "soon", want: 0, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			got, ok := parseRetryAfter(header, now)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		status    int
+		header    map[string]string
+		wantSleep time.Duration
+	}{
+		{
+			name:      "budget available",
+			status:    http.StatusOK,
+			header:    map[string]string{"X-Ratelimit-Remaining": "100.0", "X-Ratelimit-Used": "500", "X-Ratelimit-Reset": "30"},
+			wantSleep: 0,
+		},
+		{
+			name:      "budget exhausted",
+			status:    http.StatusOK,
+			header:    map[string]string{"X-Ratelimit-Remaining": "1.0", "X-Ratelimit-Used": "599", "X-Ratelimit-Reset": "30"},
+			wantSleep: 30 * time.Second,
+		},
+		{
+			name:      "too many requests with retry after",
+			status:    http.StatusTooManyRequests,
+			header:    map[string]string{"Retry-After": "5"},
+			wantSleep: 5 * time.Second,
+		},
+		{
+			name:      "no headers",
+			status:    http.StatusOK,
+			header:    map[string]string{},
+			wantSleep: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var slept time.Duration
+			limiter := newRateLimiter()
+			limiter.now = func() time.Time { return now }
+			limiter.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = d
+				return nil
+			}
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.header {
+				resp.Header.Set(k, v)
+			}
+			limiter.update(resp)
+			if err := limiter.wait(context.Background()); err != nil {
+				t.Fatalf("wait() unexpected error = %v", err)
+			}
+			if slept != tt.wantSleep {
+				t.Errorf("wait() slept %v, want %v", slept, tt.wantSleep)
+			}
+		})
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	rc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining", "598.0")
+		w.Header().Set("X-Ratelimit-Used", "2")
+		w.Header().Set("X-Ratelimit-Reset", "120")
+		w.Write([]byte(`{"kind":"Listing","data":{"children":[]}}`))
+	})
+	if _, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25); err != nil {
+		t.Fatalf("GetNewSubmissions() unexpected error = %v", err)
+	}
+	limit := rc.RateLimit()
+	if limit.Remaining != 598 || limit.Used != 2 {
+		t.Errorf("RateLimit() = %+v, want 598 remaining and 2 used", limit)
+	}
+	if d := time.Until(limit.Reset); d < 110*time.Second || d > 120*time.Second {
+		t.Errorf("RateLimit().Reset in %v, want about 120s", d)
+	}
+}
+
+func TestRetryHttpRequestRetryAfter(t *testing.T) {
+	callCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callCount++
+		if callCount == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	// the exponential delay is long enough to fail the test if Retry-After is ignored
+	start := time.Now()
+	resp, err := retryHttpRequest(&http.Client{}, req, 3, time.Minute)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("retryHttpRequest() = %v, %v", resp, err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("retryHttpRequest() did not honour Retry-After")
+	}
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(rc *Client) error
+	}{
+		{
+			name: "listing",
+			call: func(rc *Client) error {
+				_, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25)
+				return err
+			},
+		},
+		{
+			name: "form post",
+			call: func(rc *Client) error {
+				return rc.DeleteComment(context.Background(), "t1_abc")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			rc := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				if r.Method == http.MethodPost && r.FormValue("id") != "t1_abc" {
+					t.Errorf("retried request form id = %q, want t1_abc", r.FormValue("id"))
+				}
+				w.Write([]byte(`{"kind":"Listing","data":{"children":[]},"json":{"errors":[]}}`))
+			})
+			start := time.Now()
+			if err := tt.call(rc); err != nil {
+				t.Fatalf("unexpected error = %v", err)
+			}
+			if calls != 2 {
+				t.Errorf("sent %d requests, want 2", calls)
+			}
+			if d := time.Since(start); d < time.Second {
+				t.Errorf("retried after %v, want the Retry-After of 1s", d)
+			}
+		})
+	}
+}
+
+func TestClientPausesOutsideTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("pauses for longer than the http timeout")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/access_token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"test-token","token_type":"bearer","expires_in":86400,"scope":"*"}`))
+	})
+	reset := (httpTimeout + time.Second).Seconds()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining", "0")
+		w.Header().Set("X-Ratelimit-Used", "600")
+		w.Header().Set("X-Ratelimit-Reset", strconv.FormatFloat(reset, 'f', -1, 64))
+		w.Write([]byte(`{"kind":"Listing","data":{"children":[]}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	// the client of NewRedditClient, with its httpTimeout
+	rc, err := NewRedditClient("id", "secret", "test-user", "password", "", 0, false,
+		WithBaseURL(server.URL),
+		WithAuthURL(server.URL+"/api/v1/access_token"),
+	)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25); err != nil {
+			t.Fatalf("GetNewSubmissions() call %d unexpected error = %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,898 @@
+// Package reddittest provides an in-memory fake of the Reddit API endpoints
+// used by reddit.Client, for use in tests and offline local runs.
+package reddittest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+// BotUsername is the user the clients of Client act as.
+const BotUsername = "_SSD_BOT_"
+
+const (
+	tokenTTL        = 24 * time.Hour
+	defaultBudget   = 600
+	rateLimitWindow = 10 * time.Minute
+)
+
+// Comment is a comment stored by the fake server.
+type Comment struct {
+	ID        string
+	Name      string
+	Subreddit string
+	Author    string
+	ParentID  string
+	LinkID    string
+	Body      string
+	Created   time.Time
+}
+
+// inboxItem is a message delivered to the inbox of a user.
+type inboxItem struct {
+	To      string
+	Message reddit.Message
+}
+
+// Fault is a canned response returned instead of the real handler output.
+type Fault struct {
+	Status int
+	Header http.Header
+	Body   string
+}
+
+// Server is an in-memory Reddit API. The zero value is not usable, create one
+// with New or NewServer.
+type Server struct {
+	// URL is the base URL of the running server, empty until started.
+	URL string
+
+	srv *httptest.Server
+	mux *http.ServeMux
+
+	mu          sync.Mutex
+	nextID      int
+	tokens      map[string]string // access token -> username, empty for application only tokens
+	refresh     map[string]string // refresh token -> username
+	submissions map[string][]reddit.Submission
+	comments    []Comment
+	inbox       []inboxItem
+	faults      map[string][]Fault
+	requests    map[string]int
+
+	budget      int
+	used        int
+	windowStart time.Time
+}
+
+// New creates a fake Reddit API that is not listening yet. It implements
+// http.Handler so it can be served on any address.
+func New() *Server {
+	s := &Server{
+		mux:         http.NewServeMux(),
+		tokens:      map[string]string{},
+		refresh:     map[string]string{},
+		submissions: map[string][]reddit.Submission{},
+		faults:      map[string][]Fault{},
+		requests:    map[string]int{},
+		budget:      defaultBudget,
+		windowStart: time.Now(),
+	}
+	s.mux.HandleFunc("POST /api/v1/access_token", s.handleToken)
+	s.mux.HandleFunc("GET /r/{subreddit}/new", s.authorized(s.handleNewSubmissions))
+	s.mux.HandleFunc("GET /comments/{id}", s.authorized(s.handleComments))
+	s.mux.HandleFunc("GET /user/{username}/comments", s.authorized(s.handleUserComments))
+	s.mux.HandleFunc("POST /api/comment", s.authorized(s.handleSubmitComment))
+	s.mux.HandleFunc("POST /api/editusertext", s.authorized(s.handleEditComment))
+	s.mux.HandleFunc("POST /api/del", s.authorized(s.handleDeleteComment))
+	s.mux.HandleFunc("GET /message/{mailbox}", s.authorized(s.handleMessages))
+	s.mux.HandleFunc("POST /api/read_message", s.authorized(s.handleReadMessage))
+	s.mux.HandleFunc("POST /api/compose", s.authorized(s.handleCompose))
+	s.mux.HandleFunc("GET /api/info", s.authorized(s.handleInfo))
+	s.mux.HandleFunc("POST /api/morechildren", s.authorized(s.handleMoreChildren))
+	return s
+}
+
+// NewServer creates and starts a fake Reddit API on a local loopback address.
+// Callers should call Close when finished.
+func NewServer() *Server {
+	s := New()
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down a server started with NewServer.
+func (s *Server) Close() {
+	if s.srv != nil {
+		s.srv.Close()
+	}
+}
+
+// Options returns the reddit.Client options that point a client at this server.
+func (s *Server) Options() []reddit.Option {
+	opts := []reddit.Option{
+		reddit.WithBaseURL(s.URL),
+		reddit.WithAuthURL(s.URL + "/api/v1/access_token"),
+	}
+	if s.srv != nil {
+		opts = append(opts, reddit.WithHTTPClient(s.srv.Client()))
+	}
+	return opts
+}
+
+// Client returns a client of this server acting as BotUsername with the
+// password grant, failing t when it cannot be created.
+func (s *Server) Client(t testing.TB) *reddit.Client {
+	t.Helper()
+	rc, err := reddit.NewRedditClient("id", "secret", BotUsername, "password", "", 0, false, s.Options()...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	return rc
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	var fault *Fault
+	if queue := s.faults[r.URL.Path]; len(queue) > 0 {
+		fault = &queue[0]
+		s.faults[r.URL.Path] = queue[1:]
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		for k, v := range fault.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(fault.Status)
+		w.Write([]byte(fault.Body))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// AddSubmission seeds a submission, newest first. ID, Name and CreatedUTC are
+// generated when empty. It returns the stored submission.
+func (s *Server) AddSubmission(submission reddit.Submission) reddit.Submission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if submission.ID == "" {
+		submission.ID = s.newID()
+	}
+	if submission.Name == "" {
+		submission.Name = "t3_" + submission.ID
+	}
+	if submission.CreatedUTC == 0 {
+		submission.CreatedUTC = float64(time.Now().Unix())
+	}
+	if submission.Permalink == "" {
+		submission.Permalink = fmt.Sprintf("/r/%s/comments/%s/", submission.Subreddit, submission.ID)
+	}
+	sub := strings.ToLower(submission.Subreddit)
+	s.submissions[sub] = append([]reddit.Submission{submission}, s.submissions[sub]...)
+	return submission
+}
+
+// AddComment seeds a top level comment on a submission and returns it.
+func (s *Server) AddComment(submissionId, author, body string) Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addComment("t3_"+submissionId, "t3_"+submissionId, author, body)
+}
+
+// AddReply seeds a comment replying to the comment or submission with the
+// given fullname and returns it. The author of the parent and every user
+// mentioned with u/name get the comment in their inbox.
+func (s *Server) AddReply(parentFullname, author, body string) Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	linkId := parentFullname
+	if parent, ok := s.findComment(parentFullname); ok {
+		linkId = parent.LinkID
+	}
+	return s.addComment(parentFullname, linkId, author, body)
+}
+
+// Comments returns every comment on a submission, oldest first.
+func (s *Server) Comments(submissionId string) []Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []Comment
+	for _, c := range s.comments {
+		if c.LinkID == "t3_"+submissionId {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// CommentsBy returns every comment made by author, oldest first. Use it to
+// inspect what the bot posted.
+func (s *Server) CommentsBy(author string) []Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []Comment
+	for _, c := range s.comments {
+		if strings.EqualFold(c.Author, author) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Unread returns the unread inbox items of username, oldest first.
+func (s *Server) Unread(username string) []reddit.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []reddit.Message
+	for _, item := range s.inbox {
+		if strings.EqualFold(item.To, username) && item.Message.New {
+			res = append(res, item.Message)
+		}
+	}
+	return res
+}
+
+// AddMessage seeds a private message and returns it.
+func (s *Server) AddMessage(from, to, subject, body string) reddit.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addMessage(from, to, subject, body)
+}
+
+// MessagesTo returns every private message sent to username, read or not,
+// oldest first.
+func (s *Server) MessagesTo(username string) []reddit.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []reddit.Message
+	for _, item := range s.inbox {
+		if strings.EqualFold(item.To, username) && !item.Message.WasComment {
+			res = append(res, item.Message)
+		}
+	}
+	return res
+}
+
+// AddRefreshToken registers a refresh token that logs in as username with
+// the refresh_token grant.
+func (s *Server) AddRefreshToken(refreshToken, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[refreshToken] = username
+}
+
+// Inject queues a fault for the next request to path. Multiple faults for
+// the same path are returned in order.
+func (s *Server) Inject(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = append(s.faults[path], fault)
+}
+
+// InjectStatus queues times responses with the given status code for path.
+func (s *Server) InjectStatus(path string, status int, times int) {
+	for i := 0; i < times; i++ {
+		s.Inject(path, Fault{Status: status, Body: fmt.Sprintf(`{"message": %q, "error": %d}`, http.StatusText(status), status)})
+	}
+}
+
+// InjectAPIError queues a 200 response for path whose api_type=json body
+// lists a single error, the way Reddit reports RATELIMIT or THREAD_LOCKED.
+func (s *Server) InjectAPIError(path, code, message string) {
+	body, _ := json.Marshal(map[string]any{"json": map[string]any{"errors": [][]string{{code, message, ""}}}})
+	s.Inject(path, Fault{Status: http.StatusOK, Body: string(body)})
+}
+
+// Requests returns how many requests were received for path, faults included.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// SetRateLimit sets the number of requests allowed in the current ten minute
+// window. Once exhausted, API requests get a 429 with a Retry-After header.
+func (s *Server) SetRateLimit(budget int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budget = budget
+	s.used = 0
+	s.windowStart = time.Now()
+}
+
+// spendBudget counts a request against the rate limit and writes the
+// X-Ratelimit-* headers. It reports false when the budget is exhausted.
+func (s *Server) spendBudget(w http.ResponseWriter) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.windowStart) >= rateLimitWindow {
+		s.used = 0
+		s.windowStart = time.Now()
+	}
+	reset := int(math.Ceil((rateLimitWindow - time.Since(s.windowStart)).Seconds()))
+	if s.used >= s.budget {
+		w.Header().Set("Retry-After", strconv.Itoa(reset))
+		return false
+	}
+	s.used++
+	w.Header().Set("X-Ratelimit-Remaining", strconv.FormatFloat(float64(s.budget-s.used), 'f', 1, 64))
+	w.Header().Set("X-Ratelimit-Used", strconv.Itoa(s.used))
+	w.Header().Set("X-Ratelimit-Reset", strconv.Itoa(reset))
+	return true
+}
+
+// newID returns a new base36 id. Callers must hold s.mu.
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.FormatInt(int64(1_000_000+s.nextID), 36)
+}
+
+// addComment stores a comment. Callers must hold s.mu.
+func (s *Server) addComment(parentId, linkId, author, body string) Comment {
+	id := s.newID()
+	c := Comment{
+		ID:       id,
+		Name:     "t1_" + id,
+		Author:   author,
+		ParentID: parentId,
+		LinkID:   linkId,
+		Body:     body,
+		Created:  time.Now(),
+	}
+	if submission, ok := s.findSubmission(strings.TrimPrefix(linkId, "t3_")); ok {
+		c.Subreddit = submission.Subreddit
+	}
+	s.comments = append(s.comments, c)
+	s.notify(c)
+	return c
+}
+
+var mentionPattern = regexp.MustCompile(`(?i)(?:^|[^\w/])/?u/([\w-]+)`)
+
+// notify delivers a new comment to the inbox of the parent author and of the
+// users it mentions. Callers must hold s.mu.
+func (s *Server) notify(c Comment) {
+	var parentAuthor, kind, linkTitle string
+	if submission, ok := s.findSubmission(strings.TrimPrefix(c.LinkID, "t3_")); ok {
+		linkTitle = submission.Title
+		if c.ParentID == submission.Name {
+			parentAuthor, kind = submission.Author, reddit.MessagePostReply
+		}
+	}
+	if parent, ok := s.findComment(c.ParentID); ok {
+		parentAuthor, kind = parent.Author, reddit.MessageCommentReply
+	}
+	if parentAuthor != "" && !strings.EqualFold(parentAuthor, c.Author) {
+		s.inbox = append(s.inbox, inboxItem{To: parentAuthor, Message: c.message(kind, linkTitle)})
+	}
+
+	notified := map[string]bool{strings.ToLower(parentAuthor): true, strings.ToLower(c.Author): true}
+	// mentions are often escaped by the markdown editor, e.g. u/\_name\_
+	for _, match := range mentionPattern.FindAllStringSubmatch(strings.ReplaceAll(c.Body, `\`, ""), -1) {
+		if notified[strings.ToLower(match[1])] {
+			continue
+		}
+		notified[strings.ToLower(match[1])] = true
+		s.inbox = append(s.inbox, inboxItem{To: match[1], Message: c.message(reddit.MessageUsernameMention, linkTitle)})
+	}
+}
+
+// addMessage delivers a private message. Callers must hold s.mu.
+func (s *Server) addMessage(from, to, subject, body string) reddit.Message {
+	id := s.newID()
+	m := reddit.Message{
+		ID:         id,
+		Name:       "t4_" + id,
+		Author:     from,
+		Dest:       to,
+		Subject:    subject,
+		Body:       body,
+		New:        true,
+		CreatedUTC: float64(time.Now().Unix()),
+	}
+	s.inbox = append(s.inbox, inboxItem{To: to, Message: m})
+	return m
+}
+
+// findComment looks up a comment by fullname. Callers must hold s.mu.
+func (s *Server) findComment(fullname string) (Comment, bool) {
+	for _, c := range s.comments {
+		if c.Name == fullname {
+			return c, true
+		}
+	}
+	return Comment{}, false
+}
+
+// findSubmission looks up a submission by id. Callers must hold s.mu.
+func (s *Server) findSubmission(id string) (reddit.Submission, bool) {
+	for _, submissions := range s.submissions {
+		for _, submission := range submissions {
+			if submission.ID == id {
+				return submission, true
+			}
+		}
+	}
+	return reddit.Submission{}, false
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if _, _, ok := r.BasicAuth(); !ok {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	var username string
+	known := true
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		username = r.PostForm.Get("username")
+	case "refresh_token":
+		username, known = s.refresh[r.PostForm.Get("refresh_token")]
+	case "client_credentials":
+	default:
+		known = false
+	}
+	token := "token-" + s.newID()
+	if known {
+		s.tokens[token] = username
+	}
+	s.mu.Unlock()
+
+	if !known {
+		// reddit reports bad grants with a 200 and an error field
+		writeJSON(w, map[string]any{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   int(tokenTTL.Seconds()),
+		"scope":        "*",
+	})
+}
+
+type userHandlerFunc func(w http.ResponseWriter, r *http.Request, username string)
+
+// authorized rejects requests without a bearer token issued by this server.
+func (s *Server) authorized(next userHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "bearer ")
+		s.mu.Lock()
+		username, known := s.tokens[token]
+		s.mu.Unlock()
+		if !ok || !known {
+			writeError(w, http.StatusUnauthorized)
+			return
+		}
+		if !s.spendBudget(w) {
+			writeError(w, http.StatusTooManyRequests)
+			return
+		}
+		next(w, r, username)
+	}
+}
+
+func (s *Server) handleNewSubmissions(w http.ResponseWriter, r *http.Request, _ string) {
+	s.mu.Lock()
+	submissions := s.submissions[strings.ToLower(r.PathValue("subreddit"))]
+	res := page(r, submissions, "t3", func(s reddit.Submission) string { return s.Name })
+	s.mu.Unlock()
+
+	writeJSON(w, res)
+}
+
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request, _ string) {
+	id := r.PathValue("id")
+	limit := parseLimit(r)
+	s.mu.Lock()
+	submission, ok := s.findSubmission(id)
+	var children []reddit.Children[any]
+	var more []string
+	for _, c := range s.comments {
+		if c.ParentID != "t3_"+id {
+			continue
+		}
+		if len(children) < limit {
+			children = append(children, reddit.Children[any]{Kind: "t1", Data: c.submissionComment()})
+		} else {
+			more = append(more, c.ID)
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	if len(more) > 0 {
+		// reddit lists the comments left out in a "more" child
+		children = append(children, reddit.Children[any]{Kind: "more", Data: reddit.More{
+			Count:    len(more),
+			Name:     "t1_" + more[0],
+			ID:       more[0],
+			ParentID: "t3_" + id,
+			Children: more,
+		}})
+	}
+
+	post := listing([]reddit.Children[reddit.Submission]{{Kind: "t3", Data: submission}})
+	writeJSON(w, []any{post, listing(children)})
+}
+
+func (s *Server) handleUserComments(w http.ResponseWriter, r *http.Request, _ string) {
+	username := r.PathValue("username")
+	s.mu.Lock()
+	var comments []reddit.UserComment
+	for i := len(s.comments) - 1; i >= 0; i-- {
+		if strings.EqualFold(s.comments[i].Author, username) {
+			comments = append(comments, s.comments[i].userComment())
+		}
+	}
+	res := page(r, comments, "t1", func(c reddit.UserComment) string { return c.Name })
+	s.mu.Unlock()
+
+	writeJSON(w, res)
+}
+
+func (s *Server) handleSubmitComment(w http.ResponseWriter, r *http.Request, username string) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	if username == "" {
+		writeJSON(w, map[string]any{"json": map[string]any{"errors": [][]string{{"USER_REQUIRED", "Please log in to do that.", ""}}}})
+		return
+	}
+	thingId := r.PostForm.Get("thing_id")
+	text := r.PostForm.Get("text")
+
+	s.mu.Lock()
+	linkId := thingId
+	if parent, ok := s.findComment(thingId); ok {
+		linkId = parent.LinkID
+	}
+	submission, ok := s.findSubmission(strings.TrimPrefix(linkId, "t3_"))
+	var apiErr []string
+	switch {
+	case !ok || submission.IsRemoved():
+		apiErr = []string{"DELETED_LINK", "the link you are commenting on has been deleted", "parent"}
+	case submission.Locked:
+		apiErr = []string{"THREAD_LOCKED", "that comment thread has been locked", ""}
+	case submission.Archived:
+		apiErr = []string{"TOO_OLD", "that's a piece of history now; it's too late to reply to it", "parent"}
+	}
+	var c Comment
+	if apiErr == nil {
+		c = s.addComment(thingId, linkId, username, text)
+	}
+	s.mu.Unlock()
+
+	if apiErr != nil {
+		writeJSON(w, map[string]any{"json": map[string]any{"errors": [][]string{apiErr}}})
+		return
+	}
+	writeJSON(w, map[string]any{
+		"json": map[string]any{
+			"errors": [][]string{},
+			"data": map[string]any{
+				"things": []reddit.Children[reddit.SubmissionComment]{{Kind: "t1", Data: c.submissionComment()}},
+			},
+		},
+	})
+}
+
+func (s *Server) handleEditComment(w http.ResponseWriter, r *http.Request, username string) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	c, apiErr := s.ownComment(r.PostForm.Get("thing_id"), username)
+	if apiErr == nil {
+		c.Body = r.PostForm.Get("text")
+	}
+	s.mu.Unlock()
+
+	if apiErr != nil {
+		writeJSON(w, map[string]any{"json": map[string]any{"errors": [][]string{apiErr}}})
+		return
+	}
+	writeJSON(w, map[string]any{
+		"json": map[string]any{
+			"errors": [][]string{},
+			"data": map[string]any{
+				"things": []reddit.Children[reddit.SubmissionComment]{{Kind: "t1", Data: c.submissionComment()}},
+			},
+		},
+	})
+}
+
+func (s *Server) handleDeleteComment(w http.ResponseWriter, r *http.Request, username string) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	c, apiErr := s.ownComment(r.PostForm.Get("id"), username)
+	if apiErr == nil {
+		c.Author = "[deleted]"
+		c.Body = "[deleted]"
+	}
+	s.mu.Unlock()
+
+	// reddit answers /api/del with an empty object whether or not it deleted anything
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request, username string) {
+	if username == "" {
+		writeError(w, http.StatusForbidden)
+		return
+	}
+	mailbox := reddit.Mailbox(r.PathValue("mailbox"))
+	s.mu.Lock()
+	var messages []reddit.Message
+	for i := len(s.inbox) - 1; i >= 0; i-- {
+		item := s.inbox[i]
+		if !strings.EqualFold(item.To, username) {
+			continue
+		}
+		m := item.Message
+		switch {
+		case mailbox == reddit.MailboxInbox,
+			mailbox == reddit.MailboxUnread && m.New,
+			mailbox == reddit.MailboxMentions && m.Type == reddit.MessageUsernameMention,
+			mailbox == reddit.MailboxCommentReplies && m.Type == reddit.MessageCommentReply,
+			mailbox == reddit.MailboxMessages && !m.WasComment:
+			messages = append(messages, m)
+		}
+	}
+	res := page(r, messages, "t1", func(m reddit.Message) string { return m.Name })
+	s.mu.Unlock()
+
+	for i := range res.Data.Children {
+		if !res.Data.Children[i].Data.WasComment {
+			res.Data.Children[i].Kind = "t4"
+		}
+	}
+	writeJSON(w, res)
+}
+
+func (s *Server) handleReadMessage(w http.ResponseWriter, r *http.Request, username string) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	ids := map[string]bool{}
+	for _, id := range strings.Split(r.PostForm.Get("id"), ",") {
+		ids[id] = true
+	}
+	s.mu.Lock()
+	for i := range s.inbox {
+		if strings.EqualFold(s.inbox[i].To, username) && ids[s.inbox[i].Message.Name] {
+			s.inbox[i].Message.New = false
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request, _ string) {
+	var children []reddit.Children[any]
+	s.mu.Lock()
+	for _, fullname := range strings.Split(r.URL.Query().Get("id"), ",") {
+		if c, ok := s.findComment(fullname); ok {
+			children = append(children, reddit.Children[any]{Kind: "t1", Data: c.submissionComment()})
+		} else if submission, ok := s.findSubmission(strings.TrimPrefix(fullname, "t3_")); ok && strings.HasPrefix(fullname, "t3_") {
+			children = append(children, reddit.Children[any]{Kind: "t3", Data: submission})
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, listing(children))
+}
+
+func (s *Server) handleMoreChildren(w http.ResponseWriter, r *http.Request, _ string) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	ids := map[string]bool{}
+	for _, id := range strings.Split(r.PostForm.Get("children"), ",") {
+		ids[id] = true
+	}
+	linkId := r.PostForm.Get("link_id")
+	var things []reddit.Children[reddit.SubmissionComment]
+	s.mu.Lock()
+	for _, c := range s.comments {
+		if c.LinkID == linkId && ids[c.ID] {
+			things = append(things, reddit.Children[reddit.SubmissionComment]{Kind: "t1", Data: c.submissionComment()})
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{"json": map[string]any{"errors": [][]string{}, "data": map[string]any{"things": things}}})
+}
+
+func (s *Server) handleCompose(w http.ResponseWriter, r *http.Request, username string) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	var apiErr []string
+	switch {
+	case username == "":
+		apiErr = []string{"USER_REQUIRED", "Please log in to do that.", ""}
+	case r.PostForm.Get("to") == "":
+		apiErr = []string{"NO_USER", "please enter a username", "to"}
+	case r.PostForm.Get("subject") == "":
+		apiErr = []string{"NO_SUBJECT", "please enter a subject", "subject"}
+	case r.PostForm.Get("text") == "":
+		apiErr = []string{"NO_TEXT", "we need something here", "text"}
+	}
+	if apiErr != nil {
+		writeJSON(w, map[string]any{"json": map[string]any{"errors": [][]string{apiErr}}})
+		return
+	}
+	s.mu.Lock()
+	s.addMessage(username, r.PostForm.Get("to"), r.PostForm.Get("subject"), r.PostForm.Get("text"))
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{"json": map[string]any{"errors": [][]string{}}})
+}
+
+// ownComment finds a comment by fullname that username is allowed to change.
+// Callers must hold s.mu.
+func (s *Server) ownComment(fullname, username string) (*Comment, []string) {
+	for i := range s.comments {
+		c := &s.comments[i]
+		if c.Name != fullname || c.Author == "[deleted]" {
+			continue
+		}
+		if !strings.EqualFold(c.Author, username) {
+			return nil, []string{"NOT_AUTHOR", "you can't do that", "thing_id"}
+		}
+		return c, nil
+	}
+	return nil, []string{"NO_THING_ID", "can't find that thing", "thing_id"}
+}
+
+func (c Comment) submissionComment() reddit.SubmissionComment {
+	return reddit.SubmissionComment{
+		Subreddit: c.Subreddit,
+		ID:        c.ID,
+		Author:    c.Author,
+		ParentID:  c.ParentID,
+		Body:      c.Body,
+		Name:      c.Name,
+		LinkID:    c.LinkID,
+	}
+}
+
+func (c Comment) message(kind, linkTitle string) reddit.Message {
+	subjects := map[string]string{
+		reddit.MessageUsernameMention: "username mention",
+		reddit.MessageCommentReply:    "comment reply",
+		reddit.MessagePostReply:       "post reply",
+	}
+	return reddit.Message{
+		ID:         c.ID,
+		Name:       c.Name,
+		Author:     c.Author,
+		Subject:    subjects[kind],
+		Body:       c.Body,
+		Subreddit:  c.Subreddit,
+		ParentID:   c.ParentID,
+		LinkTitle:  linkTitle,
+		Context:    fmt.Sprintf("/r/%s/comments/%s/_/%s/?context=3", c.Subreddit, strings.TrimPrefix(c.LinkID, "t3_"), c.ID),
+		Type:       kind,
+		WasComment: true,
+		New:        true,
+		CreatedUTC: float64(c.Created.Unix()),
+	}
+}
+
+func (c Comment) userComment() reddit.UserComment {
+	return reddit.UserComment{
+		Subreddit: c.Subreddit,
+		ID:        c.ID,
+		Author:    c.Author,
+		ParentID:  c.ParentID,
+		Body:      c.Body,
+		LinkID:    c.LinkID,
+		Name:      c.Name,
+	}
+}
+
+func listing[T any](children []reddit.Children[T]) reddit.Listing[T] {
+	if children == nil {
+		children = []reddit.Children[T]{}
+	}
+	return reddit.Listing[T]{
+		Kind: "Listing",
+		Data: reddit.ListingData[T]{
+			Dist:     len(children),
+			Children: children,
+		},
+	}
+}
+
+// page slices items, ordered newest first, according to the limit, after and
+// before query parameters and sets the listing cursors like Reddit does.
+func page[T any](r *http.Request, items []T, kind string, name func(T) string) reddit.Listing[T] {
+	limit := parseLimit(r)
+	query := r.URL.Query()
+	index := func(fullname string) int {
+		for i, item := range items {
+			if name(item) == fullname {
+				return i
+			}
+		}
+		return -1
+	}
+
+	start, end := 0, min(limit, len(items))
+	if after := query.Get("after"); after != "" {
+		start = index(after) + 1
+		if start == 0 {
+			start = len(items)
+		}
+		end = min(start+limit, len(items))
+	} else if before := query.Get("before"); before != "" {
+		end = max(index(before), 0)
+		start = max(end-limit, 0)
+	}
+
+	var children []reddit.Children[T]
+	for _, item := range items[start:end] {
+		children = append(children, reddit.Children[T]{Kind: kind, Data: item})
+	}
+	res := listing(children)
+	if end < len(items) && end > start {
+		res.Data.After = name(items[end-1])
+	}
+	if start > 0 && end > start {
+		res.Data.Before = name(items[start])
+	}
+	return res
+}
+
+func parseLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return 25
+	}
+	return min(limit, 100)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"message": %q, "error": %d}`, http.StatusText(status), status)
+}
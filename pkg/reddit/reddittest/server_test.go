@@ -0,0 +1,139 @@
+package reddittest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/reddit"
+)
+
+func newClient(t *testing.T, s *Server) *reddit.Client {
+	t.Helper()
+	rc, err := reddit.NewRedditClient("id", "secret", "bot", "password", "", 0, false, s.Options()...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() unexpected error = %v", err)
+	}
+	return rc
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	rc := newClient(t, s)
+
+	s.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] older"})
+	newest := s.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] newer"})
+	s.AddComment(newest.ID, "SSDBot", "specs")
+
+	submissions, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25)
+	if err != nil {
+		t.Fatalf("GetNewSubmissions() unexpected error = %v", err)
+	}
+	if len(submissions) != 2 || submissions[0].ID != newest.ID {
+		t.Fatalf("GetNewSubmissions() = %v, want newest first", submissions)
+	}
+
+	posted, err := rc.SubmitComment(context.Background(), newest.ID, "bot specs")
+	if err != nil {
+		t.Fatalf("SubmitComment() unexpected error = %v", err)
+	}
+	if posted.Name != "t1_"+posted.ID || posted.LinkID != newest.Name {
+		t.Errorf("SubmitComment() = %+v, want a comment on %s", posted, newest.Name)
+	}
+
+	comments, err := rc.GetCommentsBySubmissionId(context.Background(), newest.ID, 100)
+	if err != nil {
+		t.Fatalf("GetCommentsBySubmissionId() unexpected error = %v", err)
+	}
+	if len(comments) != 2 || comments[1].Author != "bot" {
+		t.Errorf("GetCommentsBySubmissionId() = %v, want the posted comment listed", comments)
+	}
+
+	userComments, err := rc.GetUserNewestComments(context.Background(), 25)
+	if err != nil {
+		t.Fatalf("GetUserNewestComments() unexpected error = %v", err)
+	}
+	if len(userComments) != 1 || userComments[0].LinkID != newest.Name {
+		t.Errorf("GetUserNewestComments() = %v, want one comment on %s", userComments, newest.Name)
+	}
+
+	stored := s.CommentsBy("bot")
+	if len(stored) != 1 || stored[0].Body != "bot specs" || stored[0].Name != posted.Name {
+		t.Errorf("CommentsBy() = %v, want the posted comment", stored)
+	}
+}
+
+func TestServerInjectStatus(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	rc := newClient(t, s)
+
+	s.InjectStatus("/r/buildapcsales/new", http.StatusServiceUnavailable, 1)
+	if _, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25); err == nil {
+		t.Error("GetNewSubmissions() expected error for injected 503")
+	}
+	if _, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25); err != nil {
+		t.Errorf("GetNewSubmissions() unexpected error after fault was consumed = %v", err)
+	}
+	if got := s.Requests("/r/buildapcsales/new"); got != 2 {
+		t.Errorf("Requests() = %d, want 2", got)
+	}
+}
+
+func TestServerRateLimit(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	rc := newClient(t, s)
+
+	s.SetRateLimit(3)
+	if _, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25); err != nil {
+		t.Fatalf("GetNewSubmissions() unexpected error = %v", err)
+	}
+	if limit := rc.RateLimit(); limit.Remaining != 2 || limit.Used != 1 {
+		t.Errorf("RateLimit() = %+v, want 2 remaining and 1 used", limit)
+	}
+
+	s.SetRateLimit(0)
+	if _, err := rc.GetNewSubmissions(context.Background(), "buildapcsales", 25); err == nil {
+		t.Error("GetNewSubmissions() expected error once the budget is exhausted")
+	}
+	if limit := rc.RateLimit(); limit.Remaining != 0 || time.Until(limit.Reset) <= 0 {
+		t.Errorf("RateLimit() = %+v, want an exhausted budget until the window resets", limit)
+	}
+}
+
+func TestServerGrantTypes(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddRefreshToken("refresh", "bot")
+	submission := s.AddSubmission(reddit.Submission{Subreddit: "buildapcsales", Title: "[SSD] test"})
+
+	rc, err := reddit.NewRedditClient("id", "", "bot", "", "", 0, false, append(s.Options(), reddit.WithRefreshToken("refresh"))...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() with refresh token unexpected error = %v", err)
+	}
+	if _, err := rc.SubmitComment(context.Background(), submission.ID, "hello"); err != nil {
+		t.Errorf("SubmitComment() unexpected error = %v", err)
+	}
+
+	if _, err := reddit.NewRedditClient("id", "", "bot", "", "", 0, false, append(s.Options(), reddit.WithRefreshToken("revoked"))...); !errors.Is(err, reddit.ErrUnauthorized) {
+		t.Errorf("NewRedditClient() with unknown refresh token error = %v, want %v", err, reddit.ErrUnauthorized)
+	}
+
+	appOnly, err := reddit.NewRedditClient("id", "secret", "", "", "", 0, false, append(s.Options(), reddit.WithClientCredentials())...)
+	if err != nil {
+		t.Fatalf("NewRedditClient() with client credentials unexpected error = %v", err)
+	}
+	if submissions, err := appOnly.GetNewSubmissions(context.Background(), "buildapcsales", 25); err != nil || len(submissions) != 1 {
+		t.Errorf("GetNewSubmissions() = %v, %v, want the seeded submission", submissions, err)
+	}
+	if _, err := appOnly.SubmitComment(context.Background(), submission.ID, "hello"); !errors.Is(err, reddit.ErrUserContextRequired) {
+		t.Errorf("SubmitComment() error = %v, want %v", err, reddit.ErrUserContextRequired)
+	}
+	if _, err := appOnly.GetUserNewestComments(context.Background(), 25); !errors.Is(err, reddit.ErrUserContextRequired) {
+		t.Errorf("GetUserNewestComments() error = %v, want %v", err, reddit.ErrUserContextRequired)
+	}
+}
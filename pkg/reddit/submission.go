@@ -1,53 +1,139 @@
 package reddit
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// maxCommentsLimit is the most comments Reddit returns in one listing.
+	maxCommentsLimit = 100
+	// MaxMoreChildren is the most comment ids GetMoreComments accepts.
+	MaxMoreChildren = 100
+	// maxMoreCommentsRequests caps the requests CommentedBy makes for comments
+	// left out of a thread.
+	maxMoreCommentsRequests = 5
+)
+
 // Submission represents a Reddit post/submission.
 type Submission struct {
-	ID            string `json:"id"`
-	Subreddit     string `json:"subreddit"`
-	Title         string `json:"title"`
-	Name          string `json:"name"`
-	LinkFlairText string `json:"link_flair_text"`
+	ID                string  `json:"id"`
+	Subreddit         string  `json:"subreddit"`
+	Title             string  `json:"title"`
+	Author            string  `json:"author"`
+	Name              string  `json:"name"`
+	LinkFlairText     string  `json:"link_flair_text"`
+	URL               string  `json:"url"`
+	Domain            string  `json:"domain"`
+	Permalink         string  `json:"permalink"`
+	Selftext          string  `json:"selftext"`
+	CreatedUTC        float64 `json:"created_utc"`
+	Locked            bool    `json:"locked"`
+	Archived          bool    `json:"archived"`
+	RemovedByCategory string  `json:"removed_by_category"`
+	Over18            bool    `json:"over_18"`
+	Stickied          bool    `json:"stickied"`
+}
+
+// Created returns the time the submission was posted.
+func (s Submission) Created() time.Time {
+	sec, frac := math.Modf(s.CreatedUTC)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// IsRemoved reports whether the submission was removed by a moderator,
+// Reddit or deleted by its author.
+func (s Submission) IsRemoved() bool {
+	return s.RemovedByCategory != ""
 }
 
 // GetNewSubmissions fetches the newest submissions from a subreddit.
-func (rc *Client) GetNewSubmissions(subreddit string, limit int) ([]Submission, error) {
-	redditUrl := fmt.Sprintf("https://oauth.reddit.com/r/%s/new?limit=%v", subreddit, limit)
-	req, err := rc.newRequest("GET", redditUrl, nil)
+func (rc *Client) GetNewSubmissions(ctx context.Context, subreddit string, limit int) ([]Submission, error) {
+	listing, err := rc.GetNewSubmissionsPage(ctx, subreddit, ListingOptions{Limit: limit})
 	if err != nil {
-		log.Error().Msgf("Error creating request: %v", err)
 		return nil, err
 	}
-	resp, err := rc.httpClient.Do(req)
-	if err != nil {
-		log.Error().Msgf("Error sending request: %v", err)
-		return nil, err
-	}
-	if resp.StatusCode/100 != 2 {
-		log.Error().Msgf("Error request: %v", resp.Status)
-		return nil, fmt.Errorf("received non OK status code: %s", resp.Status)
+	return listing.Items(), nil
+}
+
+// GetNewSubmissionsPage fetches a single page of the newest submissions from a subreddit.
+func (rc *Client) GetNewSubmissionsPage(ctx context.Context, subreddit string, opts ListingOptions) (Listing[Submission], error) {
+	return getListing[Submission](ctx, rc, fmt.Sprintf("/r/%s/new", subreddit), opts)
+}
+
+// NewSubmissions returns a paginator over the newest submissions of a subreddit,
+// fetching at most maxPages pages.
+func (rc *Client) NewSubmissions(subreddit string, opts ListingOptions, maxPages int) *Paginator[Submission] {
+	return NewPaginator(func(ctx context.Context, opts ListingOptions) (Listing[Submission], error) {
+		return rc.GetNewSubmissionsPage(ctx, subreddit, opts)
+	}, opts, maxPages)
+}
+
+// SubmissionPoller fetches new submissions of a subreddit and remembers the
+// newest one it has seen, so each poll only returns submissions posted since.
+type SubmissionPoller struct {
+	rc        *Client
+	subreddit string
+	pageSize  int
+	maxPages  int
+	newest    string
+}
+
+// NewSubmissionPoller creates a poller walking up to maxPages pages of pageSize submissions.
+func NewSubmissionPoller(rc *Client, subreddit string, pageSize, maxPages int) *SubmissionPoller {
+	return &SubmissionPoller{
+		rc:        rc,
+		subreddit: subreddit,
+		pageSize:  pageSize,
+		maxPages:  maxPages,
 	}
-	defer resp.Body.Close()
+}
 
-	var listings Listing[Submission]
-	err = json.NewDecoder(resp.Body).Decode(&listings)
-	if err != nil {
-		log.Error().Err(err).Msg("Error decoding response body")
-		return nil, err
+// Poll returns the submissions posted since the previous poll, newest first.
+// The first poll returns up to maxPages pages. If the newest fullname from the
+// previous poll is not found within maxPages, everything fetched is returned.
+func (p *SubmissionPoller) Poll(ctx context.Context) ([]Submission, error) {
+	pages := p.rc.NewSubmissions(p.subreddit, ListingOptions{Limit: p.pageSize}, p.maxPages)
+	var submissions []Submission
+	for pages.HasNext() {
+		page, err := pages.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		seen := false
+		for _, submission := range page {
+			if p.newest != "" && submission.Name == p.newest {
+				seen = true
+				break
+			}
+			submissions = append(submissions, submission)
+		}
+		if seen {
+			break
+		}
 	}
-	var posts []Submission
-	for _, child := range listings.Data.Children {
-		posts = append(posts, child.Data)
+	if len(submissions) > 0 {
+		p.newest = submissions[0].Name
 	}
-	return posts, nil
+	return submissions, nil
+}
+
+// Newest returns the fullname of the newest submission seen so far.
+func (p *SubmissionPoller) Newest() string {
+	return p.newest
+}
+
+// SetNewest moves the poller cursor, e.g. back to a previous Newest value so
+// submissions that failed to process are returned again by the next poll.
+func (p *SubmissionPoller) SetNewest(fullname string) {
+	p.newest = fullname
 }
 
 // SubmissionComment represents a comment on a Reddit submission.
@@ -60,83 +146,177 @@ type SubmissionComment struct {
 	AuthorFullname string `json:"author_fullname"`
 	Body           string `json:"body"`
 	Name           string `json:"name"`
+	LinkID         string `json:"link_id"`
 	IsSubmitter    bool   `json:"is_submitter"`
 }
 
-// GetCommentsBySubmissionId fetches comments for a specific submission.
-func (rc *Client) GetCommentsBySubmissionId(submissionId string, limit int) ([]SubmissionComment, error) {
-	redditUrl := fmt.Sprintf("https://oauth.reddit.com/comments/%s?limit=%v&depth=1", submissionId, limit)
-	req, err := rc.newRequest("GET", redditUrl, nil)
+// More is a placeholder for comments left out of a comment listing. Fetch
+// them with GetMoreComments.
+type More struct {
+	Count    int      `json:"count"`
+	Name     string   `json:"name"`
+	ID       string   `json:"id"`
+	ParentID string   `json:"parent_id"`
+	Depth    int      `json:"depth"`
+	Children []string `json:"children"`
+}
+
+// GetCommentsBySubmissionId fetches top level comments for a specific submission.
+func (rc *Client) GetCommentsBySubmissionId(ctx context.Context, submissionId string, limit int) ([]SubmissionComment, error) {
+	comments, _, err := rc.GetSubmissionComments(ctx, submissionId, limit)
+	return comments, err
+}
+
+// GetSubmissionComments fetches up to limit top level comments for a
+// submission. It also returns the ids of the comments left out, which can be
+// fetched with GetMoreComments.
+func (rc *Client) GetSubmissionComments(ctx context.Context, submissionId string, limit int) ([]SubmissionComment, []string, error) {
+	redditUrl := fmt.Sprintf("%s/comments/%s?limit=%v&depth=1&raw_json=1", rc.baseURL, submissionId, limit)
+	req, err := rc.newRequest(ctx, "GET", redditUrl, nil)
 	if err != nil {
 		log.Error().Msgf("Error creating request: %v", err)
-		return nil, err
+		return nil, nil, err
 	}
-	resp, err := rc.httpClient.Do(req)
+	resp, err := rc.do(req)
 	if err != nil {
 		log.Error().Msgf("Error sending request: %v", err)
-		return nil, err
-	}
-	if resp.StatusCode/100 != 2 {
-		log.Error().Msgf("Error request: %v", resp.Status)
-		return nil, fmt.Errorf("received non OK status code: %s", resp.Status)
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
+	if err := rc.checkResponse(resp); err != nil {
+		return nil, nil, err
+	}
 
-	var listings []Listing[SubmissionComment]
+	var listings []Listing[json.RawMessage]
 	err = json.NewDecoder(resp.Body).Decode(&listings)
 	if err != nil {
 		log.Error().Err(err).Msg("Error decoding response body")
-		return nil, err
+		return nil, nil, err
 	}
 	if len(listings) < 2 {
 		log.Error().Msgf("Expected at least 2 listings, got %d", len(listings))
-		return nil, fmt.Errorf("invalid response format: expected at least 2 listings, got %d", len(listings))
+		return nil, nil, fmt.Errorf("invalid response format: expected at least 2 listings, got %d", len(listings))
 	}
-	var submissionComments []SubmissionComment
 	// The second element contains comments (first element is the post itself)
-	for _, child := range listings[1].Data.Children {
-		submissionComments = append(submissionComments, child.Data)
+	return splitComments(listings[1].Data.Children)
+}
+
+// GetMoreComments fetches comments left out of a comment listing by id, at
+// most MaxMoreChildren at a time. It also returns the ids of comments left
+// out of this response.
+func (rc *Client) GetMoreComments(ctx context.Context, submissionId string, ids []string) ([]SubmissionComment, []string, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+	data := url.Values{}
+	data.Set("link_id", "t3_"+submissionId)
+	data.Set("children", strings.Join(ids, ","))
+	data.Set("limit_children", "false")
+	data.Set("raw_json", "1")
+
+	var res struct {
+		Things []Children[json.RawMessage] `json:"things"`
+	}
+	err := rc.postForm(ctx, "/api/morechildren", data, &res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return splitComments(res.Things)
+}
+
+// splitComments decodes the comments of a listing and collects the ids of
+// the comments its "more" children left out.
+func splitComments(children []Children[json.RawMessage]) ([]SubmissionComment, []string, error) {
+	var comments []SubmissionComment
+	var more []string
+	for _, child := range children {
+		switch child.Kind {
+		case "t1":
+			var comment SubmissionComment
+			if err := json.Unmarshal(child.Data, &comment); err != nil {
+				return nil, nil, fmt.Errorf("decoding comment: %w", err)
+			}
+			comments = append(comments, comment)
+		case "more":
+			var m More
+			if err := json.Unmarshal(child.Data, &m); err != nil {
+				return nil, nil, fmt.Errorf("decoding more comments: %w", err)
+			}
+			more = append(more, m.Children...)
+		}
 	}
-	return submissionComments, nil
+	return comments, more, nil
+}
+
+// SubmitComment posts a comment on a submission and returns the created comment.
+// Errors reported in the response body are returned as *SubmitError.
+// It needs a user context.
+func (rc *Client) SubmitComment(ctx context.Context, postId, text string) (*SubmissionComment, error) {
+	return rc.Reply(ctx, "t3_"+postId, text)
 }
 
-// SubmitComment posts a comment on a submission.
-func (rc *Client) SubmitComment(postId, text string) error {
+// Reply posts a comment replying to a submission, comment or private message
+// and returns the created comment. parentFullname is the fullname of the
+// thing replied to, e.g. t3_abc or t1_abc.
+// Errors reported in the response body are returned as *SubmitError.
+// It needs a user context.
+func (rc *Client) Reply(ctx context.Context, parentFullname, text string) (*SubmissionComment, error) {
+	if err := rc.requireUserContext(); err != nil {
+		return nil, err
+	}
 	data := url.Values{}
-	data.Set("api_type", "json")
 	data.Set("text", text)
-	data.Set("thing_id", "t3_"+postId)
+	data.Set("thing_id", parentFullname)
 
-	req, err := rc.newRequest("POST", "https://oauth.reddit.com/api/comment", strings.NewReader(data.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	if err != nil {
-		log.Error().Err(err).Msg("Error creating request")
-		return err
+	var res struct {
+		Things []Children[SubmissionComment] `json:"things"`
 	}
-	resp, err := rc.httpClient.Do(req)
+	err := rc.postForm(ctx, "/api/comment", data, &res)
 	if err != nil {
-		log.Error().Msgf("Error sending request: %v", err)
-		return err
+		return nil, err
 	}
-	if resp.StatusCode/100 != 2 {
-		log.Error().Msgf("Error request: %v", resp.Status)
-		return fmt.Errorf("received non OK status code: %s", resp.Status)
+	if len(res.Things) == 0 {
+		return nil, fmt.Errorf("invalid response format: no comment returned")
 	}
-	defer resp.Body.Close()
-	return nil
+	return &res.Things[0].Data, nil
 }
 
 // IsCommentedByUser checks if a user has commented on a submission.
-func (rc *Client) IsCommentedByUser(submissionId string, author string) bool {
-	comments, err := rc.GetCommentsBySubmissionId(submissionId, 100)
+func (rc *Client) IsCommentedByUser(ctx context.Context, submissionId string, author string) (bool, error) {
+	commenter, err := rc.CommentedBy(ctx, submissionId, author)
+	return commenter != "", err
+}
+
+// CommentedBy returns the first of authors found among the top level
+// comments of a submission, or an empty string when none of them commented.
+// Comments left out of large threads are fetched as well, up to
+// maxMoreCommentsRequests extra requests.
+func (rc *Client) CommentedBy(ctx context.Context, submissionId string, authors ...string) (string, error) {
+	comments, more, err := rc.GetSubmissionComments(ctx, submissionId, maxCommentsLimit)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get comments for checking author")
-		return false
+		return "", err
 	}
-	for _, comment := range comments {
-		if comment.Author == author {
-			return true
+	for requests := 0; ; requests++ {
+		for _, comment := range comments {
+			for _, author := range authors {
+				if strings.EqualFold(comment.Author, author) {
+					return comment.Author, nil
+				}
+			}
+		}
+		if len(more) == 0 {
+			return "", nil
+		}
+		if requests == maxMoreCommentsRequests {
+			log.Warn().Msgf("Stopped looking for %v after %d more comments requests, %d comments left on %s", authors, requests, len(more), submissionId)
+			return "", nil
+		}
+		batch := more[:min(len(more), MaxMoreChildren)]
+		var next []string
+		comments, next, err = rc.GetMoreComments(ctx, submissionId, batch)
+		if err != nil {
+			return "", err
 		}
+		more = append(more[len(batch):], next...)
 	}
-	return false
 }
@@ -0,0 +1,84 @@
+package reddit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Token is an OAuth access token and the time it expires, with the grant
+// and user it was issued for.
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	Expiry      time.Time `json:"expiry"`
+	GrantType   GrantType `json:"grant_type"`
+	// Username is empty for application only tokens.
+	Username string `json:"username,omitempty"`
+}
+
+// TokenStore persists the access token so restarts can reuse it instead of
+// logging in again.
+type TokenStore interface {
+	// Load returns the stored token, or nil when nothing has been stored yet.
+	Load() (*Token, error)
+	Save(token Token) error
+}
+
+// FileTokenStore is a TokenStore that keeps the token as JSON in a file only
+// readable by its owner.
+type FileTokenStore struct {
+	path string
+}
+
+// NewFileTokenStore creates a token store backed by the file at path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (s *FileTokenStore) Load() (*Token, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading token file: %w", err)
+	}
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("decoding token file: %w", err)
+	}
+	return &token, nil
+}
+
+// Save writes the token to a temporary file and renames it over the old one,
+// so a crash never leaves a truncated token behind.
+func (s *FileTokenStore) Save(token Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("encoding token: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("setting token file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing token file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replacing token file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package reddit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStore(path)
+
+	token, err := store.Load()
+	if err != nil || token != nil {
+		t.Fatalf("Load() on a missing file = %v, %v, want nil, nil", token, err)
+	}
+
+	want := Token{AccessToken: "stored-token", Expiry: time.Now().Add(time.Hour).Truncate(time.Second)}
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() unexpected error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("token file permissions = %v, want 0600", perm)
+	}
+
+	token, err = store.Load()
+	if err != nil || token == nil {
+		t.Fatalf("Load() = %v, %v", token, err)
+	}
+	if token.AccessToken != want.AccessToken || !token.Expiry.Equal(want.Expiry) {
+		t.Errorf("Load() = %+v, want %+v", token, want)
+	}
+}
+
+func TestClientTokenStore(t *testing.T) {
+	tests := []struct {
+		name       string
+		stored     *Token
+		opts       []Option
+		wantLogins int
+		wantToken  string
+	}{
+		{
+			name:       "no stored token logs in and saves",
+			stored:     nil,
+			wantLogins: 1,
+			wantToken:  "fresh-token",
+		},
+		{
+			name:       "valid stored token is reused",
+			stored:     &Token{AccessToken: "stored-token", Expiry: time.Now().Add(12 * time.Hour), GrantType: GrantPassword, Username: "user"},
+			wantLogins: 0,
+			wantToken:  "stored-token",
+		},
+		{
+			name:       "expiring stored token is refreshed",
+			stored:     &Token{AccessToken: "stored-token", Expiry: time.Now().Add(time.Minute), GrantType: GrantPassword, Username: "user"},
+			wantLogins: 1,
+			wantToken:  "fresh-token",
+		},
+		{
+			name:       "token of another user is ignored",
+			stored:     &Token{AccessToken: "stored-token", Expiry: time.Now().Add(12 * time.Hour), GrantType: GrantPassword, Username: "other"},
+			wantLogins: 1,
+			wantToken:  "fresh-token",
+		},
+		{
+			name:       "token of another grant is ignored",
+			stored:     &Token{AccessToken: "stored-token", Expiry: time.Now().Add(12 * time.Hour), GrantType: GrantClientCredentials},
+			wantLogins: 1,
+			wantToken:  "fresh-token",
+		},
+		{
+			name:       "application only token is reused",
+			stored:     &Token{AccessToken: "stored-token", Expiry: time.Now().Add(12 * time.Hour), GrantType: GrantClientCredentials},
+			opts:       []Option{WithClientCredentials()},
+			wantLogins: 0,
+			wantToken:  "stored-token",
+		},
+		{
+			name:       "token without a grant is ignored",
+			stored:     &Token{AccessToken: "stored-token", Expiry: time.Now().Add(12 * time.Hour)},
+			wantLogins: 1,
+			wantToken:  "fresh-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logins := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logins++
+				w.Write([]byte(`{"access_token":"fresh-token","token_type":"bearer","expires_in":86400,"scope":"*"}`))
+			}))
+			defer server.Close()
+
+			store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+			if tt.stored != nil {
+				if err := store.Save(*tt.stored); err != nil {
+					t.Fatalf("Save() unexpected error = %v", err)
+				}
+			}
+
+			opts := append([]Option{WithAuthURL(server.URL), WithTokenStore(store)}, tt.opts...)
+			_, err := NewRedditClient("id", "secret", "user", "password", "", 0, false, opts...)
+			if err != nil {
+				t.Fatalf("NewRedditClient() unexpected error = %v", err)
+			}
+			if logins != tt.wantLogins {
+				t.Errorf("NewRedditClient() logged in %d times, want %d", logins, tt.wantLogins)
+			}
+			token, err := store.Load()
+			if err != nil || token == nil || token.AccessToken != tt.wantToken {
+				t.Fatalf("stored token = %+v, %v, want %s", token, err, tt.wantToken)
+			}
+			if tt.wantLogins > 0 && (token.GrantType == "" || token.GrantType == GrantPassword && token.Username != "user") {
+				t.Errorf("stored token = %+v, want it tied to the grant and user", token)
+			}
+		})
+	}
+}
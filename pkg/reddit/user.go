@@ -1,10 +1,8 @@
 package reddit
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-
-	"github.com/rs/zerolog/log"
 )
 
 // UserComment represents a comment made by a user.
@@ -21,33 +19,26 @@ type UserComment struct {
 }
 
 // GetUserNewestComments fetches the newest comments by the authenticated user.
-func (rc *Client) GetUserNewestComments(limit int) ([]UserComment, error) {
-	redditUrl := fmt.Sprintf("https://oauth.reddit.com/user/%s/comments?limit=%v", rc.username, limit)
-	req, err := rc.newRequest("GET", redditUrl, nil)
-	if err != nil {
-		log.Error().Msgf("Error creating request: %v", err)
-		return nil, err
-	}
-	resp, err := rc.httpClient.Do(req)
+// It needs a user context.
+func (rc *Client) GetUserNewestComments(ctx context.Context, limit int) ([]UserComment, error) {
+	listing, err := rc.GetUserCommentsPage(ctx, ListingOptions{Limit: limit})
 	if err != nil {
-		log.Error().Msgf("Error sending request: %v", err)
 		return nil, err
 	}
-	if resp.StatusCode/100 != 2 {
-		log.Error().Msgf("Error request: %v", resp.Status)
-		return nil, fmt.Errorf("received non OK status code: %s", resp.Status)
-	}
-	defer resp.Body.Close()
+	return listing.Items(), nil
+}
 
-	var listing Listing[UserComment]
-	err = json.NewDecoder(resp.Body).Decode(&listing)
-	if err != nil {
-		log.Error().Err(err).Msg("Error decoding response body")
-		return nil, err
-	}
-	var userComments []UserComment
-	for _, child := range listing.Data.Children {
-		userComments = append(userComments, child.Data)
+// UserComments returns a paginator over the comments of the authenticated
+// user, newest first, fetching at most maxPages pages.
+func (rc *Client) UserComments(opts ListingOptions, maxPages int) *Paginator[UserComment] {
+	return NewPaginator(rc.GetUserCommentsPage, opts, maxPages)
+}
+
+// GetUserCommentsPage fetches a single page of comments by the authenticated user.
+// It needs a user context.
+func (rc *Client) GetUserCommentsPage(ctx context.Context, opts ListingOptions) (Listing[UserComment], error) {
+	if err := rc.requireUserContext(); err != nil {
+		return Listing[UserComment]{}, err
 	}
-	return userComments, nil
+	return getListing[UserComment](ctx, rc, fmt.Sprintf("/user/%s/comments", rc.username), opts)
 }
@@ -0,0 +1,42 @@
+package ssd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// capacityPattern matches a capacity like "2TB", "1.5 TB" or "500gb", and the
+// pack count of one like "2TBx2" or "2x2TB". The capacity of a pack is that
+// of one of its drives.
+var capacityPattern = regexp.MustCompile(`(?i)\b(?:\d+\s*x\s*)?(\d+(?:\.\d+)?)\s*(gb|tb)(?:x\d+)?\b`)
+
+// ParseCapacityGB returns the first capacity in s in GB, a TB being 1000 GB,
+// e.g. 1500 for "Crucial P3 Plus 1.5TB". It returns false when s names none.
+func ParseCapacityGB(s string) (float64, bool) {
+	match := capacityPattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	if strings.EqualFold(match[2], "tb") {
+		n *= 1000
+	}
+	return n, true
+}
+
+// FormatCapacity formats a capacity in GB like the database, e.g. "2 TB".
+func FormatCapacity(gb float64) string {
+	if gb >= 1000 {
+		return strconv.FormatFloat(gb/1000, 'f', -1, 64) + " TB"
+	}
+	return strconv.FormatFloat(gb, 'f', -1, 64) + " GB"
+}
+
+// RemoveCapacities removes every capacity from s.
+func RemoveCapacities(s string) string {
+	return capacityPattern.ReplaceAllString(s, "")
+}
@@ -0,0 +1,53 @@
+package ssd
+
+import "testing"
+
+func TestParseCapacityGB(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   float64
+		wantOk bool
+	}{
+		{input: "Samsung 970 EVO 1TB", want: 1000, wantOk: true},
+		{input: "Crucial MX500 500GB", want: 500, wantOk: true},
+		{input: "samsung 970 evo 1 tb", want: 1000, wantOk: true},
+		{input: "Crucial P3 Plus 1.5TB", want: 1500, wantOk: true},
+		{input: "WD Blue SN580 2TBx2", want: 2000, wantOk: true},
+		{input: "WD Blue SN580 2x2TB", want: 2000, wantOk: true},
+		{input: "Crucial P3 Plus 4 x 1TB", want: 1000, wantOk: true},
+		{input: "Samsung 870 QVO 1000GB", want: 1000, wantOk: true},
+		{input: "Samsung 970 1TB for 100 dollars", want: 1000, wantOk: true},
+		{input: "Samsung 970 EVO", want: 0, wantOk: false},
+		{input: "Lexar NM790 4TBW", want: 0, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := ParseCapacityGB(tt.input)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ParseCapacityGB() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestFormatCapacity(t *testing.T) {
+	tests := []struct {
+		gb   float64
+		want string
+	}{
+		{gb: 500, want: "500 GB"},
+		{gb: 1000, want: "1 TB"},
+		{gb: 1500, want: "1.5 TB"},
+	}
+	for _, tt := range tests {
+		if got := FormatCapacity(tt.gb); got != tt.want {
+			t.Errorf("FormatCapacity(%v) = %q, want %q", tt.gb, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveCapacities(t *testing.T) {
+	if got, want := RemoveCapacities("crucial p3 plus 2tbx2 / 2x1.5 TB $99"), "crucial p3 plus  /  $99"; got != want {
+		t.Errorf("RemoveCapacities() = %q, want %q", got, want)
+	}
+}
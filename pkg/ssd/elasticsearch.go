@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 
@@ -84,9 +83,23 @@ type BoolQueryParams struct {
 }
 
 func (esRepo *EsRepository) Search(ctx context.Context, searchQuery string) ([]SSD, error) {
+	scored, err := esRepo.SearchScored(ctx, searchQuery)
+	if err != nil {
+		return nil, err
+	}
+	var res []SSD
+	for _, s := range scored {
+		res = append(res, s.SSD)
+	}
+	return res, nil
+}
+
+// SearchScored is Search keeping the score of each hit, highest first. The
+// capacity and form factor in searchQuery, if any, must match exactly.
+func (esRepo *EsRepository) SearchScored(ctx context.Context, searchQuery string) ([]ScoredSSD, error) {
 	log.Info().Msgf("searching using this query: %s", searchQuery)
 	var ssdResponse elasticutil.SearchResponse[SSD]
-	var res []SSD
+	var res []ScoredSSD
 
 	boolQuery := BoolQuery{
 		Bool: BoolQueryParams{
@@ -101,7 +114,7 @@ func (esRepo *EsRepository) Search(ctx context.Context, searchQuery string) ([]S
 	}
 	boolQuery.Bool.Must = append(boolQuery.Bool.Must, matchQuery)
 
-	capacity, ok := parseCapacity(searchQuery)
+	capacity, ok := capacityTerm(searchQuery)
 	if ok {
 		capacityQuery := map[string]interface{}{
 			"term": map[string]interface{}{
@@ -131,7 +144,7 @@ func (esRepo *EsRepository) Search(ctx context.Context, searchQuery string) ([]S
 	}
 
 	for _, hit := range ssdResponse.Hits.Hits {
-		res = append(res, hit.Source)
+		res = append(res, ScoredSSD{SSD: hit.Source, Score: hit.Score})
 	}
 	return res, nil
 }
@@ -212,18 +225,14 @@ func (esRepo *EsRepository) doSearch(ctx context.Context, query map[string]inter
 	return nil
 }
 
-// parseCapacity parses a string for a capacity in TB or GB
-func parseCapacity(s string) (int, bool) {
-	s = strings.ToUpper(s)
-	re := regexp.MustCompile(`(\d+)\s*(TB|GB)`)
-	match := re.FindStringSubmatch(s)
-	if len(match) <= 1 {
-		return 0, false
-	}
-	capacity, err := strconv.Atoi(match[1])
-	if err != nil {
-		return 0, false
+// capacityTerm returns the number of the capacity in s as the database
+// writes it, e.g. "1.5" for "1.5TB" stored as "1.5 TB", or "1" for "1000GB".
+func capacityTerm(s string) (string, bool) {
+	gb, ok := ParseCapacityGB(s)
+	if !ok {
+		return "", false
 	}
+	capacity := strings.Fields(FormatCapacity(gb))[0]
 	log.Info().Msgf("found capacity: %v", capacity)
 	return capacity, true
 }
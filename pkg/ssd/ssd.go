@@ -1,9 +1,12 @@
 package ssd
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"net/url"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -25,6 +28,35 @@ type Repository interface {
 	Search(ctx context.Context, s string) ([]SSD, error)
 }
 
+// ScoredSSD is a search result with the relevance score the repository gave it.
+type ScoredSSD struct {
+	SSD
+	Score float64 `json:"score"`
+}
+
+// ScoredSearcher is a Repository that can tell how relevant each search
+// result is.
+type ScoredSearcher interface {
+	SearchScored(ctx context.Context, s string) ([]ScoredSSD, error)
+}
+
+// SearchScored searches repo, with scores when it is a ScoredSearcher and a
+// zero score for every result otherwise.
+func SearchScored(ctx context.Context, repo Repository, s string) ([]ScoredSSD, error) {
+	if scored, ok := repo.(ScoredSearcher); ok {
+		return scored.SearchScored(ctx, s)
+	}
+	ssds, err := repo.Search(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]ScoredSSD, 0, len(ssds))
+	for _, ssd := range ssds {
+		res = append(res, ScoredSSD{SSD: ssd})
+	}
+	return res, nil
+}
+
 // SSD represents a solid-state drive with its specifications.
 type SSD struct {
 	DriveID      string     `json:"driveId"`
@@ -71,6 +103,22 @@ type SSDBasic struct {
 	FormFactor   string `json:"formFactor"`
 }
 
+var driveURLPattern = regexp.MustCompile(`techpowerup\.com/ssd-specs/[^\s)]*\.d(\d+)`)
+
+// ParseDriveIDs returns the drive IDs of every TechPowerUp drive link in s,
+// e.g. the links in a comment rendered by ToMarkdown, without duplicates.
+func ParseDriveIDs(s string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, match := range driveURLPattern.FindAllStringSubmatch(s, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			ids = append(ids, match[1])
+		}
+	}
+	return ids
+}
+
 // GetHMBSize returns the HMB (Host Memory Buffer) size, or "N/A" if unknown.
 func (ssd SSD) GetHMBSize() string {
 	if ssd.Hmb == "Unknown" {
@@ -87,16 +135,72 @@ func (ssd SSD) GetDramSize() string {
 	return ssd.Dram
 }
 
+// camelCamelRegions are the camelcamelcamel sites by region, the US one
+// being the default.
+var camelCamelRegions = map[string]string{
+	"us": CamelCamelURL,
+	"ca": "https://ca.camelcamelcamel.com/search?sq=",
+	"uk": "https://uk.camelcamelcamel.com/search?sq=",
+	"de": "https://de.camelcamelcamel.com/search?sq=",
+	"fr": "https://fr.camelcamelcamel.com/search?sq=",
+	"it": "https://it.camelcamelcamel.com/search?sq=",
+	"es": "https://es.camelcamelcamel.com/search?sq=",
+	"au": "https://au.camelcamelcamel.com/search?sq=",
+	"jp": "https://jp.camelcamelcamel.com/search?sq=",
+}
+
+// IsPriceRegion reports whether region has a price history site, e.g. "ca".
+func IsPriceRegion(region string) bool {
+	_, ok := camelCamelRegions[strings.ToLower(region)]
+	return ok
+}
+
 // ToMarkdown converts SSD to Markdown format to support
 // formatting in a reddit comment submission
 func (ssd SSD) ToMarkdown() string {
-	ref := fmt.Sprintf(
-		"[^(TechPowerup Database)](%s) ^| [^( Github)](%s) ^| [^(Issues)](%s)",
-		TechPowerUpURL,
-		GitHubURL,
-		GitHubIssuesURL,
-	)
+	return ssd.ToMarkdownRegion("")
+}
+
+// ToMarkdownRegion is ToMarkdown with the price history linking to the
+// camelcamelcamel site of region, e.g. "uk". Unknown regions use the US site.
+func (ssd SSD) ToMarkdownRegion(region string) string {
+	return ssd.specsMarkdown(region) + "\n\n---\n" + markdownFooter()
+}
+
+// MultiMarkdownRegion renders several SSDs in one comment, side by side when
+// they are capacities of the same model and one spec block each otherwise.
+func MultiMarkdownRegion(ssds []SSD, region string) string {
+	if len(ssds) == 1 {
+		return ssds[0].ToMarkdownRegion(region)
+	}
+	sameModel := true
+	for _, ssd := range ssds[1:] {
+		if !strings.EqualFold(ssd.Manufacturer, ssds[0].Manufacturer) || !strings.EqualFold(ssd.Name, ssds[0].Name) {
+			sameModel = false
+		}
+	}
+	if sameModel {
+		return CompareMarkdownRegion(ssds, region)
+	}
+	blocks := make([]string, 0, len(ssds))
+	for _, ssd := range ssds {
+		blocks = append(blocks, ssd.specsMarkdown(region))
+	}
+	return strings.Join(blocks, "\n\n---\n\n") + "\n\n---\n" + markdownFooter()
+}
+
+// priceHistoryURL links to the camelcamelcamel search for the SSD on the site
+// of region, the US one for unknown regions.
+func (ssd SSD) priceHistoryURL(region string) string {
+	priceURL, ok := camelCamelRegions[strings.ToLower(region)]
+	if !ok {
+		priceURL = CamelCamelURL
+	}
+	return priceURL + url.QueryEscape(ssd.Manufacturer+" "+ssd.Name+" "+ssd.Capacity)
+}
 
+// specsMarkdown is the spec block of ToMarkdownRegion, without the footer.
+func (ssd SSD) specsMarkdown(region string) string {
 	arr := []string{
 		fmt.Sprintf("The %s %s %s is a *%s* SSD.", ssd.Manufacturer, ssd.Name, ssd.Capacity, ssd.Flash.Type),
 		fmt.Sprintf("* Interface: **%s**", ssd.Interface),
@@ -108,10 +212,116 @@ func (ssd SSD) ToMarkdown() string {
 		fmt.Sprintf("* NAND Type: **%s**", ssd.Flash.Type),
 		fmt.Sprintf("* R/W: **%s - %s**", ssd.SeqRead, ssd.SeqWrite),
 		fmt.Sprintf("* Endurance: **%s**", ssd.Endurance),
-		fmt.Sprintf("* Price History: **[camelcamelcamel](%s)**", CamelCamelURL+url.QueryEscape(ssd.Manufacturer+" "+ssd.Name+" "+ssd.Capacity)),
+		fmt.Sprintf("* Price History: **[camelcamelcamel](%s)**", ssd.priceHistoryURL(region)),
 		fmt.Sprintf("* Detailed Link: **[TechPowerUp SSD Database](%s)**", ssd.URL),
 		fmt.Sprintf("* Variations: **[TechPowerUp SSD](%s)**", TechPowerUpQueryURL+url.QueryEscape(ssd.Manufacturer+" "+ssd.Name)),
-		fmt.Sprintf("---\n%s", ref),
 	}
 	return strings.Join(arr, "\n\n")
 }
+
+// CompareMarkdown renders the specs of several SSDs side by side as a
+// Markdown table, one column per SSD.
+func CompareMarkdown(ssds []SSD) string {
+	return CompareMarkdownRegion(ssds, "")
+}
+
+// CompareMarkdownRegion is CompareMarkdown with the price history of each SSD
+// linking to the camelcamelcamel site of region.
+func CompareMarkdownRegion(ssds []SSD, region string) string {
+	rows := []struct {
+		label string
+		value func(SSD) string
+	}{
+		{"Interface", func(s SSD) string { return s.Interface }},
+		{"Form Factor", func(s SSD) string { return s.FormFactor }},
+		{"Controller", func(s SSD) string { return s.Controller.Manufacturer + " " + s.Controller.Name }},
+		{"DRAM", SSD.GetDramSize},
+		{"HMB", SSD.GetHMBSize},
+		{"NAND Brand", func(s SSD) string { return s.Flash.Manufacturer }},
+		{"NAND Type", func(s SSD) string { return s.Flash.Type }},
+		{"R/W", func(s SSD) string { return s.SeqRead + " - " + s.SeqWrite }},
+		{"Endurance", func(s SSD) string { return s.Endurance }},
+		{"Price History", func(s SSD) string { return fmt.Sprintf("[camelcamelcamel](%s)", s.priceHistoryURL(region)) }},
+		{"Detailed Link", func(s SSD) string { return fmt.Sprintf("[TechPowerUp](%s)", s.URL) }},
+	}
+
+	var b strings.Builder
+	b.WriteString("|")
+	for _, ssd := range ssds {
+		fmt.Fprintf(&b, "|%s %s %s", ssd.Manufacturer, ssd.Name, ssd.Capacity)
+	}
+	b.WriteString("|\n|:-" + strings.Repeat("|:-", len(ssds)) + "|\n")
+	for _, row := range rows {
+		fmt.Fprintf(&b, "|**%s**", row.label)
+		for _, ssd := range ssds {
+			fmt.Fprintf(&b, "|%s", row.value(ssd))
+		}
+		b.WriteString("|\n")
+	}
+	b.WriteString("\n---\n" + markdownFooter())
+	return b.String()
+}
+
+// VariantsMarkdown renders the capacities a model is sold in as a Markdown
+// table, smallest first. The SSDs are expected to share manufacturer and name.
+func VariantsMarkdown(ssds []SSD) string {
+	if len(ssds) == 0 {
+		return ""
+	}
+	sorted := slices.Clone(ssds)
+	slices.SortStableFunc(sorted, func(a, b SSD) int {
+		aGB, _ := ParseCapacityGB(a.Capacity)
+		bGB, _ := ParseCapacityGB(b.Capacity)
+		return cmp.Compare(aGB, bGB)
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "The %s %s comes in %d variants:\n\n", sorted[0].Manufacturer, sorted[0].Name, len(sorted))
+	b.WriteString("|Capacity|Controller|DRAM|NAND|Endurance|Detailed Link|\n|:-|:-|:-|:-|:-|:-|\n")
+	for _, ssd := range sorted {
+		fmt.Fprintf(&b, "|%s|%s %s|%s|%s %s|%s|[TechPowerUp](%s)|\n",
+			ssd.Capacity,
+			ssd.Controller.Manufacturer, ssd.Controller.Name,
+			ssd.GetDramSize(),
+			ssd.Flash.Manufacturer, ssd.Flash.Type,
+			ssd.Endurance,
+			ssd.URL,
+		)
+	}
+	b.WriteString("\n---\n" + markdownFooter())
+	return b.String()
+}
+
+// capacityNotePrefix starts every note of CapacityNoteMarkdown.
+const capacityNotePrefix = "*Specs shown for the "
+
+// CapacityNoteMarkdown notes that the specs of a comment are of this
+// capacity variant of the drive instead of the requested one, e.g. "2 TB",
+// which the database lacks.
+func (ssd SSD) CapacityNoteMarkdown(requested string) string {
+	return fmt.Sprintf("%s%s %s %s variant; the %s may differ in endurance/DRAM.*\n\n",
+		capacityNotePrefix, ssd.Manufacturer, ssd.Name, ssd.Capacity, requested)
+}
+
+// CapacityNote returns the notes of CapacityNoteMarkdown a comment starts
+// with, or an empty string when it has none.
+func CapacityNote(body string) string {
+	var b strings.Builder
+	for strings.HasPrefix(body, capacityNotePrefix) {
+		note, rest, _ := strings.Cut(body, "\n\n")
+		b.WriteString(note + "\n\n")
+		body = rest
+	}
+	return b.String()
+}
+
+// markdownFooter links to the data source and the project from the end of
+// a comment.
+func markdownFooter() string {
+	return fmt.Sprintf(
+		"[^(TechPowerup Database)](%s) ^| [^( Github)](%s) ^| [^(Issues)](%s)",
+		TechPowerUpURL,
+		GitHubURL,
+		GitHubIssuesURL,
+	)
+}
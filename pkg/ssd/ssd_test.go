@@ -1,68 +1,93 @@
 package ssd
 
 import (
+	"strings"
 	"testing"
 )
 
-func TestParseCapacity(t *testing.T) {
+func TestCapacityTerm(t *testing.T) {
 	tests := []struct {
 		name         string
 		input        string
-		wantCapacity int
+		wantCapacity string
 		wantOk       bool
 	}{
 		{
 			name:         "TB capacity",
 			input:        "Samsung 970 EVO 1TB",
-			wantCapacity: 1,
+			wantCapacity: "1",
 			wantOk:       true,
 		},
 		{
 			name:         "GB capacity",
 			input:        "Crucial MX500 500GB",
-			wantCapacity: 500,
+			wantCapacity: "500",
 			wantOk:       true,
 		},
 		{
 			name:         "2TB capacity",
 			input:        "WD Blue 2TB NVMe SSD",
-			wantCapacity: 2,
+			wantCapacity: "2",
+			wantOk:       true,
+		},
+		{
+			name:         "decimal capacity",
+			input:        "Crucial P3 Plus 1.5TB",
+			wantCapacity: "1.5",
+			wantOk:       true,
+		},
+		{
+			name:         "GB written as TB",
+			input:        "Samsung 970 EVO 1000GB",
+			wantCapacity: "1",
+			wantOk:       true,
+		},
+		{
+			name:         "pack of drives",
+			input:        "WD Blue SN580 2TBx2",
+			wantCapacity: "2",
+			wantOk:       true,
+		},
+		{
+			name:         "count before the capacity",
+			input:        "WD Blue SN580 2x2TB",
+			wantCapacity: "2",
 			wantOk:       true,
 		},
 		{
 			name:         "no capacity",
 			input:        "Samsung 970 EVO",
-			wantCapacity: 0,
+			wantCapacity: "",
 			wantOk:       false,
 		},
 		{
 			name:         "lowercase tb",
 			input:        "samsung 970 evo 1tb",
-			wantCapacity: 1,
+			wantCapacity: "1",
 			wantOk:       true,
 		},
 		{
 			name:         "with space before unit",
 			input:        "Samsung 970 EVO 1 TB",
-			wantCapacity: 1,
+			wantCapacity: "1",
 			wantOk:       true,
 		},
 		{
 			name:         "multiple numbers only first match",
 			input:        "Samsung 970 1TB for 100 dollars",
-			wantCapacity: 1,
+			wantCapacity: "1",
 			wantOk:       true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			capacity, ok := parseCapacity(tt.input)
+			capacity, ok := capacityTerm(tt.input)
 			if ok != tt.wantOk {
-				t.Errorf("parseCapacity(%q) ok = %v, want %v", tt.input, ok, tt.wantOk)
+				t.Errorf("capacityTerm(%q) ok = %v, want %v", tt.input, ok, tt.wantOk)
 			}
 			if capacity != tt.wantCapacity {
-				t.Errorf("parseCapacity(%q) capacity = %d, want %d", tt.input, capacity, tt.wantCapacity)
+				t.Errorf("capacityTerm(%q) capacity = %q, want %q", tt.input, capacity, tt.wantCapacity)
 			}
 		})
 	}
@@ -180,6 +205,156 @@ func TestSSDToMarkdown(t *testing.T) {
 	}
 }
 
+func TestSSDToMarkdownRegion(t *testing.T) {
+	ssd := SSD{Manufacturer: "Corsair", Name: "MP600 Mini", Capacity: "1 TB"}
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{region: "", want: "https://camelcamelcamel.com/search?sq=Corsair+MP600+Mini+1+TB"},
+		{region: "us", want: "https://camelcamelcamel.com/search?sq=Corsair+MP600+Mini+1+TB"},
+		{region: "CA", want: "https://ca.camelcamelcamel.com/search?sq=Corsair+MP600+Mini+1+TB"},
+		{region: "uk", want: "https://uk.camelcamelcamel.com/search?sq=Corsair+MP600+Mini+1+TB"},
+		{region: "mars", want: "https://camelcamelcamel.com/search?sq=Corsair+MP600+Mini+1+TB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			if got := ssd.ToMarkdownRegion(tt.region); !contains(got, "[camelcamelcamel]("+tt.want+")") {
+				t.Errorf("ToMarkdownRegion(%q) = %q, want a link to %s", tt.region, got, tt.want)
+			}
+		})
+	}
+	if ssd.ToMarkdown() != ssd.ToMarkdownRegion("us") {
+		t.Errorf("ToMarkdown() differs from ToMarkdownRegion(%q)", "us")
+	}
+}
+
+func TestCompareMarkdown(t *testing.T) {
+	ssds := []SSD{
+		{Manufacturer: "Corsair", Name: "MP600 Mini", Capacity: "1 TB", Dram: "Unknown", Controller: Controller{Manufacturer: "Phison", Name: "PS5021-E21T"}, URL: "https://www.techpowerup.com/ssd-specs/corsair-mp600-mini-1-tb.d1461"},
+		{Manufacturer: "Solidigm", Name: "P44 Pro", Capacity: "2 TB", Dram: "2048 MB", Controller: Controller{Manufacturer: "SK hynix", Name: "Aries"}, URL: "https://www.techpowerup.com/ssd-specs/solidigm-p44-pro-2-tb.d1100"},
+	}
+
+	markdown := CompareMarkdown(ssds)
+
+	expectedStrings := []string{
+		"||Corsair MP600 Mini 1 TB|Solidigm P44 Pro 2 TB|\n|:-|:-|:-|\n",
+		"|**Controller**|Phison PS5021-E21T|SK hynix Aries|\n",
+		"|**DRAM**|N/A|2048 MB|\n",
+		"|**Price History**|[camelcamelcamel](https://camelcamelcamel.com/search?sq=Corsair+MP600+Mini+1+TB)|[camelcamelcamel](https://camelcamelcamel.com/search?sq=Solidigm+P44+Pro+2+TB)|\n",
+		"[TechPowerUp](https://www.techpowerup.com/ssd-specs/solidigm-p44-pro-2-tb.d1100)",
+		GitHubURL,
+	}
+	for _, expected := range expectedStrings {
+		if !contains(markdown, expected) {
+			t.Errorf("CompareMarkdown() missing expected string %q", expected)
+		}
+	}
+}
+
+func TestMultiMarkdownRegion(t *testing.T) {
+	corsair := SSD{Manufacturer: "Corsair", Name: "MP600 Mini", Capacity: "1 TB", URL: "https://www.techpowerup.com/ssd-specs/corsair-mp600-mini-1-tb.d1461"}
+	solidigm := SSD{Manufacturer: "Solidigm", Name: "P44 Pro", Capacity: "2 TB", URL: "https://www.techpowerup.com/ssd-specs/solidigm-p44-pro-2-tb.d1100"}
+	solidigm1TB := SSD{Manufacturer: "Solidigm", Name: "P44 Pro", Capacity: "1 TB", URL: "https://www.techpowerup.com/ssd-specs/solidigm-p44-pro-1-tb.d1099"}
+
+	if got, want := MultiMarkdownRegion([]SSD{solidigm}, "uk"), solidigm.ToMarkdownRegion("uk"); got != want {
+		t.Errorf("MultiMarkdownRegion() of one drive = %q, want %q", got, want)
+	}
+	sameModel := MultiMarkdownRegion([]SSD{solidigm, solidigm1TB}, "ca")
+	if want := CompareMarkdownRegion([]SSD{solidigm, solidigm1TB}, "ca"); sameModel != want {
+		t.Errorf("MultiMarkdownRegion() of one model = %q, want the comparison table", sameModel)
+	}
+	if n := strings.Count(sameModel, "ca.camelcamelcamel.com"); n != 2 {
+		t.Errorf("MultiMarkdownRegion() of one model has %d price histories of the region, want one per drive", n)
+	}
+
+	markdown := MultiMarkdownRegion([]SSD{solidigm, corsair}, "uk")
+	if !contains(markdown, "The Solidigm P44 Pro 2 TB is a") || !contains(markdown, "The Corsair MP600 Mini 1 TB is a") || !contains(markdown, "uk.camelcamelcamel.com") {
+		t.Errorf("MultiMarkdownRegion() = %q, want a spec block per drive", markdown)
+	}
+	if n := strings.Count(markdown, markdownFooter()); n != 1 {
+		t.Errorf("MultiMarkdownRegion() has %d footers, want 1", n)
+	}
+	if ids := ParseDriveIDs(markdown); len(ids) != 2 {
+		t.Errorf("ParseDriveIDs(MultiMarkdownRegion()) = %v, want both drives", ids)
+	}
+}
+
+func TestCapacityNote(t *testing.T) {
+	drive := SSD{Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "1 TB"}
+	note := drive.CapacityNoteMarkdown("2 TB")
+	if want := "*Specs shown for the Crucial P3 Plus 1 TB variant; the 2 TB may differ in endurance/DRAM.*\n\n"; note != want {
+		t.Errorf("CapacityNoteMarkdown() = %q, want %q", note, want)
+	}
+
+	body := note + drive.ToMarkdown()
+	if got := CapacityNote(body); got != note {
+		t.Errorf("CapacityNote() = %q, want %q", got, note)
+	}
+	if got := CapacityNote(drive.ToMarkdown()); got != "" {
+		t.Errorf("CapacityNote() of a comment without a note = %q, want empty", got)
+	}
+}
+
+func TestVariantsMarkdown(t *testing.T) {
+	ssds := []SSD{
+		{Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "2 TB"},
+		{Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "500 GB"},
+		{Manufacturer: "Crucial", Name: "P3 Plus", Capacity: "1 TB"},
+	}
+
+	markdown := VariantsMarkdown(ssds)
+
+	if !contains(markdown, "The Crucial P3 Plus comes in 3 variants") {
+		t.Errorf("VariantsMarkdown() = %q, want the model and number of variants", markdown)
+	}
+	first, second, third := strings.Index(markdown, "|500 GB|"), strings.Index(markdown, "|1 TB|"), strings.Index(markdown, "|2 TB|")
+	if first < 0 || !(first < second && second < third) {
+		t.Errorf("VariantsMarkdown() = %q, want the capacities sorted smallest first", markdown)
+	}
+	if got := VariantsMarkdown(nil); got != "" {
+		t.Errorf("VariantsMarkdown(nil) = %q, want empty", got)
+	}
+}
+
+func TestParseDriveIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "rendered comment",
+			input: SSD{DriveID: "1461", URL: "https://www.techpowerup.com/ssd-specs/corsair-mp600-mini-1-tb.d1461"}.ToMarkdown(),
+			want:  []string{"1461"},
+		},
+		{
+			name:  "multiple links",
+			input: "[a](https://www.techpowerup.com/ssd-specs/a.d12) [b](https://www.techpowerup.com/ssd-specs/b.d345) [a](https://www.techpowerup.com/ssd-specs/a.d12)",
+			want:  []string{"12", "345"},
+		},
+		{
+			name:  "search link only",
+			input: "[TechPowerUp SSD](https://www.techpowerup.com/ssd-specs/?q=Corsair+MP600)",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseDriveIDs(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseDriveIDs() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseDriveIDs() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
 func TestGetHMBSize(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,83 @@
+// Package ssdtest provides an in-memory ssd.Repository for tests.
+package ssdtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/aattwwss/ssd-bot-go/pkg/ssd"
+)
+
+// Drives are a Corsair MP600 Mini 1 TB and a Solidigm P44 Pro 2 TB, the drives
+// most tests search.
+var Drives = []ssd.SSD{
+	{DriveID: "1461", Manufacturer: "Corsair", Name: "MP600 Mini", Capacity: "1 TB", URL: "https://www.techpowerup.com/ssd-specs/corsair-mp600-mini-1-tb.d1461"},
+	{DriveID: "1100", Manufacturer: "Solidigm", Name: "P44 Pro", Capacity: "2 TB", URL: "https://www.techpowerup.com/ssd-specs/solidigm-p44-pro-2-tb.d1100"},
+}
+
+// Repository is an in-memory ssd.Repository whose Search returns every drive
+// whose manufacturer appears in the search query.
+type Repository struct {
+	mu       sync.Mutex
+	ssds     []ssd.SSD
+	searches int
+}
+
+// NewRepository creates a repository holding ssds.
+func NewRepository(ssds ...ssd.SSD) *Repository {
+	return &Repository{ssds: ssds}
+}
+
+func (r *Repository) FindById(ctx context.Context, id string) (*ssd.SSD, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.ssds {
+		if s.DriveID == id {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *Repository) Insert(ctx context.Context, s ssd.SSD) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ssds = append(r.ssds, s)
+	return nil
+}
+
+func (r *Repository) Update(ctx context.Context, s ssd.SSD) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.ssds {
+		if r.ssds[i].DriveID == s.DriveID {
+			r.ssds[i] = s
+		}
+	}
+	return nil
+}
+
+func (r *Repository) SearchBasic(ctx context.Context, s string) ([]ssd.SSDBasic, error) {
+	return nil, nil
+}
+
+func (r *Repository) Search(ctx context.Context, s string) ([]ssd.SSD, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.searches++
+	var res []ssd.SSD
+	for _, candidate := range r.ssds {
+		if strings.Contains(s, strings.ToLower(candidate.Manufacturer)) {
+			res = append(res, candidate)
+		}
+	}
+	return res, nil
+}
+
+// Searches returns how many times Search was called.
+func (r *Repository) Searches() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.searches
+}